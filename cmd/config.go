@@ -0,0 +1,139 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var profile string
+
+// loadConfig sets all flags which are not given on the command line from the
+// config file. Flags are looked up by name in the selected profile first,
+// then in the section of the command, e.g.:
+//
+//	watch:
+//	  output: ndjson
+//	profiles:
+//	  deploy-status:
+//	    group-version: apps/v1
+//	    kind: deployment
+//	    path-prefix: Object,status
+//
+// The names to watch and the webhooks are read from the same places.
+func loadConfig(cmd *cobra.Command) error {
+	// the commands share mgrConfig, so first reset it to the defaults of
+	// this command
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			return
+		}
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			// Set appends to slices
+			s.Replace(sliceDefault(f.DefValue))
+			return
+		}
+		f.Value.Set(f.DefValue)
+	})
+	mgrConfig.Webhooks = nil
+	keys := []string{cmd.Name()}
+	if profile != "" {
+		p := "profiles." + profile
+		if !viper.IsSet(p) {
+			return fmt.Errorf("profile %s not found in config file %s, available: %s", profile, viper.ConfigFileUsed(), strings.Join(listProfiles(), ","))
+		}
+		keys = append([]string{p}, keys...)
+	}
+	lookup := func(name string) (interface{}, bool) {
+		for _, k := range keys {
+			if key := k + "." + name; viper.IsSet(key) {
+				return viper.Get(key), true
+			}
+		}
+		return nil, false
+	}
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed || f.Name == "profile" {
+			return
+		}
+		v, ok := lookup(f.Name)
+		if !ok {
+			return
+		}
+		if e := setFlag(f, v); e != nil {
+			err = fmt.Errorf("invalid value %v of %s in config file: %v", v, f.Name, e)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if v, ok := lookup("names"); ok && len(mgrConfig.Names) == 0 {
+		mgrConfig.Names = cast.ToStringSlice(v)
+	}
+	for _, k := range keys {
+		if key := k + ".webhooks"; viper.IsSet(key) {
+			return viper.UnmarshalKey(key, &mgrConfig.Webhooks)
+		}
+	}
+	return viper.UnmarshalKey("webhooks", &mgrConfig.Webhooks)
+}
+
+// sliceDefault parses the default of a slice flag, e.g. [a,b].
+func sliceDefault(v string) []string {
+	v = strings.TrimSuffix(strings.TrimPrefix(v, "["), "]")
+	if v == "" {
+		return nil
+	}
+	return strings.Split(v, ",")
+}
+
+func setFlag(f *pflag.Flag, v interface{}) error {
+	if s, ok := f.Value.(pflag.SliceValue); ok {
+		return s.Replace(cast.ToStringSlice(v))
+	}
+	switch vv := v.(type) {
+	case []interface{}:
+		return f.Value.Set(strings.Join(cast.ToStringSlice(vv), ","))
+	case map[string]interface{}:
+		return fmt.Errorf("map is not supported")
+	}
+	return f.Value.Set(cast.ToString(v))
+}
+
+func listProfiles() []string {
+	res := make([]string, 0)
+	for k := range viper.GetStringMap("profiles") {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func addProfileFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&profile, "profile", "", "", "named profile of the config file")
+	cmd.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		initConfig()
+		return listProfiles(), cobra.ShellCompDirectiveNoFileComp
+	})
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/nfyxhan/kubewatch/pkg/webhook"
+)
+
+const testConfig = `
+watch:
+  output: yaml
+  scope: status
+  names: [podinfo]
+profiles:
+  deploy:
+    output: ndjson
+    kind: [deploy, sts]
+    compute: ["ready=obj.status.readyReplicas"]
+    webhooks:
+    - name: profile
+      url: http://profile.example.com
+webhooks:
+- name: default
+  url: http://default.example.com
+`
+
+// runLoadConfig loads the config of a command like a fresh invocation with
+// the given command line arguments.
+func runLoadConfig(t *testing.T, name, config, profileName string, args ...string) *cobra.Command {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(config)); err != nil {
+		t.Fatalf("ReadConfig() error = %v", err)
+	}
+	cmd, _, err := rootCmd.Find([]string{name})
+	if err != nil {
+		t.Fatalf("Find(%s) error = %v", name, err)
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) { f.Changed = false })
+	if profileName != "" {
+		args = append(args, "--profile", profileName)
+	}
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	mgrConfig.Names = nil
+	if err := loadConfig(cmd); err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	return cmd
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		args       []string
+		wantOutput string
+		wantKind   string
+		wantNames  []string
+	}{
+		{name: "command section", wantOutput: "yaml", wantNames: []string{"podinfo"}},
+		{name: "profile", profile: "deploy", wantOutput: "ndjson", wantKind: "deploy,sts", wantNames: []string{"podinfo"}},
+		{name: "flag", profile: "deploy", args: []string{"-o", "json", "-k", "po"}, wantOutput: "json", wantKind: "po", wantNames: []string{"podinfo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runLoadConfig(t, "watch", testConfig, tt.profile, tt.args...)
+			if mgrConfig.Output != tt.wantOutput || mgrConfig.Objects != tt.wantKind {
+				t.Errorf("output, kind = %q, %q, want %q, %q", mgrConfig.Output, mgrConfig.Objects, tt.wantOutput, tt.wantKind)
+			}
+			if mgrConfig.Scope != "status" {
+				t.Errorf("scope = %q, want status of the command section", mgrConfig.Scope)
+			}
+			if !reflect.DeepEqual(mgrConfig.Names, tt.wantNames) {
+				t.Errorf("names = %v, want %v", mgrConfig.Names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestLoadConfigUnknownProfile(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	cmd, _, _ := rootCmd.Find([]string{"watch"})
+	if err := cmd.ParseFlags([]string{"--profile", "missing"}); err != nil {
+		t.Fatalf("ParseFlags() error = %v", err)
+	}
+	defer cmd.Flags().VisitAll(func(f *pflag.Flag) { f.Changed = false })
+	if err := loadConfig(cmd); err == nil {
+		t.Errorf("loadConfig() with an unknown profile succeeded")
+	}
+}
+
+// TestLoadConfigReset checks the settings of a previous command don't leak
+// into the next one, the commands share mgrConfig.
+func TestLoadConfigReset(t *testing.T) {
+	runLoadConfig(t, "watch", testConfig, "deploy")
+	if len(mgrConfig.Compute) != 1 || len(mgrConfig.Webhooks) != 1 {
+		t.Fatalf("compute, webhooks = %v, %v", mgrConfig.Compute, mgrConfig.Webhooks)
+	}
+	runLoadConfig(t, "replay", "", "")
+	if mgrConfig.Output != "table" || mgrConfig.Objects != "" || mgrConfig.Scope != "all" {
+		t.Errorf("output, kind, scope = %q, %q, %q, want the defaults", mgrConfig.Output, mgrConfig.Objects, mgrConfig.Scope)
+	}
+	if len(mgrConfig.Compute) != 0 || len(mgrConfig.Webhooks) != 0 {
+		t.Errorf("compute, webhooks = %v, %v, want none", mgrConfig.Compute, mgrConfig.Webhooks)
+	}
+}
+
+func TestLoadConfigWebhooks(t *testing.T) {
+	tests := []struct {
+		profile string
+		want    []webhook.Config
+	}{
+		{want: []webhook.Config{{Name: "default", URL: "http://default.example.com"}}},
+		{profile: "deploy", want: []webhook.Config{{Name: "profile", URL: "http://profile.example.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			runLoadConfig(t, "watch", testConfig, tt.profile)
+			if !reflect.DeepEqual(mgrConfig.Webhooks, tt.want) {
+				t.Errorf("webhooks = %+v, want %+v", mgrConfig.Webhooks, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/utils/strings/slices"
+
+	"github.com/nfyxhan/kubewatch/pkg/manager"
+	"github.com/nfyxhan/kubewatch/pkg/manifests"
+)
+
+func init() {
+	var opts manifests.Options
+	// generateCmd represents the generate command
+	var generateCmd = &cobra.Command{
+		Use:   "generate",
+		Short: "Generate files for running kubewatch",
+	}
+	var manifestsCmd = &cobra.Command{
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			mgrConfig.Names = args
+			return loadConfig(cmd)
+		},
+		Use:   "manifests [nameprefix]",
+		Short: "Print the manifests running kubewatch serve in a cluster",
+		Long: `Print the ServiceAccount, RBAC rules and Deployment running kubewatch serve
+in a cluster, e.g.:
+
+  kubewatch generate manifests -g apps/v1 -k deployment -p Object,status | kubectl apply -f -
+
+The ClusterRole only allows to get, list and watch the selected kinds, which
+are resolved with the current kubeconfig. All watch and serve flags which
+differ from their defaults are passed on to serve. With --kubewatches the
+KubeWatch CRD is added, the kinds of KubeWatch resources must be allowed by
+the ClusterRole.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, err := mgrConfig.GetKubeConfig()
+			if err != nil {
+				panic(err)
+			}
+			if opts.Rules, err = manager.PolicyRules(context.Background(), mgrConfig, manager.NewSchemeClient(cfg)); err != nil {
+				panic(err)
+			}
+			opts.Args = serveArgs(cmd)
+			opts.MetricsAddress = mgrConfig.MetricsBindAddress
+			opts.ProbeAddress = mgrConfig.HealthProbeBindAddress
+			opts.KubeWatches = mgrConfig.KubeWatches
+			if len(mgrConfig.Webhooks) > 0 {
+				fmt.Fprintln(os.Stderr, "webhooks of the config file are not passed on, mount the config file and add --config to the args")
+			}
+			objects, err := manifests.Objects(opts)
+			if err != nil {
+				panic(err)
+			}
+			if err := manifests.Write(os.Stdout, objects); err != nil {
+				panic(err)
+			}
+		},
+	}
+	manifestsCmd.Flags().StringVarP(&opts.Name, "name", "", "kubewatch", "name of the manifests")
+	manifestsCmd.Flags().StringVarP(&opts.Namespace, "install-namespace", "", "kubewatch", "namespace of the manifests")
+	manifestsCmd.Flags().StringVarP(&opts.Image, "image", "", "kubewatch:latest", "kubewatch image")
+	addWatchFlags(manifestsCmd)
+	addServeFlags(manifestsCmd)
+	addProfileFlag(manifestsCmd)
+	manifestsCmd.PersistentFlags().Lookup("output").DefValue = serveOutput
+	generateCmd.AddCommand(manifestsCmd)
+	rootCmd.AddCommand(generateCmd)
+}
+
+// serveArgs returns the watch and serve flags which differ from their
+// defaults, and the names to watch.
+func serveArgs(cmd *cobra.Command) []string {
+	skip := []string{"name", "install-namespace", "image", "profile", "config", "kubeconfig", "log", "help"}
+	var args []string
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if slices.Contains(skip, f.Name) || f.Value.String() == f.DefValue {
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return append(args, mgrConfig.Names...)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nfyxhan/kubewatch/pkg/plugins"
+)
+
+func init() {
+	// pluginsCmd represents the plugins command
+	var pluginsCmd = &cobra.Command{
+		Use:   "plugins",
+		Short: "Manage kubewatch plugins",
+	}
+	var listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the loaded plugins and their typed kinds",
+		Long: `List the plugins loaded from the plugin directories, the kinds they register
+and their hooks. The objects of these kinds are watched as typed objects and
+diffed by their Go field names, e.g. Status,ReadyReplicas, other kinds are
+watched as unstructured objects.
+
+Exec plugins are the executables named kubewatch-plugin-*, they are listed
+with the kinds their hooks are called for. Plugins which are rejected are
+reported on stderr, see plugins verify.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "PLUGIN\tVERSION\tKIND\tNAME\tSHORTNAME\tHOOKS")
+			for _, l := range plugins.List() {
+				hooks := strings.Join(l.Hooks(), ",")
+				if p, ok := l.Plugin.(*plugins.ExecPlugin); ok {
+					kinds := []string{"*"}
+					if info := p.Info(); len(info.Kinds) > 0 {
+						kinds = nil
+						for _, k := range info.Kinds {
+							kinds = append(kinds, k.String())
+						}
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t\t\t%s\n", l.Manifest.Name, l.Manifest.Version, strings.Join(kinds, ","), hooks)
+					continue
+				}
+				for _, o := range l.Objects {
+					kind := "<unregistered>"
+					if gvk, err := o.GVK(); err == nil {
+						kind = gvk.Kind + "." + gvk.Version
+						if gvk.Group != "" {
+							kind += "." + gvk.Group
+						}
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", l.Manifest.Name, l.Manifest.Version, kind, o.Name, o.ShortName, hooks)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				panic(err)
+			}
+		},
+	}
+	var verifyCmd = &cobra.Command{
+		Use:   "verify [file...]",
+		Short: "Check the manifests and compatibility of plugins",
+		Long: `Check the plugins of the plugin directories, or the given plugin files. Every
+plugin needs a manifest next to it, the file with the extension .yaml, e.g.
+appsv1.yaml for appsv1.so or kubewatch-plugin-podrestarts.yaml:
+
+  name: podrestarts
+  version: 0.1.0
+  apiVersion: ` + plugins.APIVersion + `
+
+Go plugins may add the goVersion they are built with, exec plugins must
+report the name and version of their manifest in the handshake. Exits with
+1 if a plugin is rejected.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ok, rejected := plugins.Verify(args)
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "FILE\tPLUGIN\tVERSION\tSTATUS")
+			for _, l := range ok {
+				fmt.Fprintf(w, "%s\t%s\t%s\tok\n", l.Path, l.Manifest.Name, l.Manifest.Version)
+			}
+			for _, r := range rejected {
+				fmt.Fprintf(w, "%s\t\t\trejected: %v\n", r.Path, r.Err)
+			}
+			if err := w.Flush(); err != nil {
+				panic(err)
+			}
+			if len(rejected) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	pluginsCmd.AddCommand(listCmd)
+	pluginsCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(pluginsCmd)
+}
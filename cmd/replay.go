@@ -0,0 +1,53 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nfyxhan/kubewatch/pkg/manager"
+)
+
+func init() {
+	// replayCmd represents the replay command
+	var replayCmd = &cobra.Command{
+		Use:   "replay file [nameprefix]",
+		Short: "Replay a journal recorded by watch --record",
+		Args:  cobra.MinimumNArgs(1),
+		Long: `Replay a journal recorded by watch --record offline, without cluster access.
+
+The recorded events are passed through the same filters, path prefix,
+path template and output format as a live watch, e.g.:
+
+  kubewatch watch -g apps/v1 -k deployment --record deploy.ndjson.gz
+  kubewatch replay deploy.ndjson.gz -p Object,status -o ndjson`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			mgrConfig.Names = args[1:]
+			return loadConfig(cmd)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			if err := manager.Replay(ctx, mgrConfig, args[0]); err != nil {
+				panic(err)
+			}
+		},
+	}
+	addWatchFlags(replayCmd)
+	addProfileFlag(replayCmd)
+	rootCmd.AddCommand(replayCmd)
+}
@@ -19,6 +19,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
@@ -27,12 +29,19 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	"github.com/nfyxhan/kubewatch/pkg/plugins"
 	"github.com/nfyxhan/kubewatch/pkg/utils"
 )
 
 var cfgFile string
 var logOut string
 
+// annotationStructuredLogs marks headless commands, which log JSON to stderr
+// by default.
+const annotationStructuredLogs = "kubewatch/structured-logs"
+
+const defaultLogOut = "/dev/null"
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "kubewatch",
@@ -66,12 +75,23 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kubewatch.yaml)")
 	rootCmd.PersistentFlags().StringVar(&utils.Kubeconfig, "kubeconfig", "", "$HOME/.kube/config")
-	rootCmd.PersistentFlags().StringVar(&logOut, "log", "/dev/null", "log file")
+	rootCmd.PersistentFlags().StringVar(&logOut, "log", defaultLogOut, "log file")
+	rootCmd.PersistentFlags().StringVar(&utils.CacheDir, "cache-dir", defaultCacheDir(), "directory of the discovery cache, shared with kubectl")
+	rootCmd.PersistentFlags().StringSliceVar(&plugins.Dirs, "plugin-dir", plugins.DefaultDirs(), "directories plugins are loaded from, default from "+plugins.PathEnv+" or ./plugins and the plugins directory next to the binary")
+	rootCmd.PersistentFlags().DurationVar(&utils.DiscoveryCacheTTL, "discovery-cache-ttl", 6*time.Hour, "how long the discovery cache is used without asking the API server")
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
 
+func defaultCacheDir() string {
+	home, err := homedir.Dir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "cache")
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
@@ -94,7 +114,7 @@ func initConfig() {
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
 
@@ -107,6 +127,12 @@ func initLog() {
 			},
 		},
 	}
+	if cmd, _, err := rootCmd.Find(os.Args[1:]); err == nil && cmd.Annotations[annotationStructuredLogs] == "true" {
+		opts.Development = false
+		if logOut == defaultLogOut {
+			logOut = ""
+		}
+	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 	if logOut != "" {
@@ -0,0 +1,86 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/nfyxhan/kubewatch/pkg/manager"
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// serveOutput is the default output format of serve, machine readable as
+// there is no terminal.
+const serveOutput = output.FormatNDJSON
+
+func init() {
+	// serveCmd represents the serve command
+	var serveCmd = &cobra.Command{
+		Annotations: map[string]string{
+			annotationStructuredLogs: "true",
+		},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			mgrConfig.Names = args
+			return loadConfig(cmd)
+		},
+		Use:   "serve [nameprefix]",
+		Short: "Watch headless as an in-cluster service",
+		Long: `Watch headless as an in-cluster service, e.g. deployed with the manifests of
+"kubewatch generate manifests".
+
+The in-cluster config is used unless a kubeconfig is given. Changes are
+written as NDJSON to stdout, logs as JSON to stderr unless --log is given.
+Health and readiness probes are served on /healthz and /readyz of
+--health-probe-address, and only the leader of multiple replicas reports
+changes.
+
+With --kubewatches the watches are configured by KubeWatch resources, which
+are added, changed and removed without restarting.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := ctrl.SetupSignalHandler()
+			mgr, err := manager.NewManager(ctx, mgrConfig, nil)
+			if err != nil {
+				panic(err)
+			}
+			log.FromContext(ctx).Info("started", "config", mgrConfig)
+			if err := mgr.Start(ctx); err != nil {
+				panic(err)
+			}
+			<-ctx.Done()
+			mgr.Close()
+		},
+	}
+	addWatchFlags(serveCmd)
+	addServeFlags(serveCmd)
+	addProfileFlag(serveCmd)
+	serveCmd.PersistentFlags().Lookup("output").DefValue = serveOutput
+	rootCmd.AddCommand(serveCmd)
+}
+
+// addServeFlags adds the flags of serve, shared with generate manifests which
+// passes them on.
+func addServeFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&mgrConfig.MetricsBindAddress, "metrics-address", "m", ":6666", "metrics address")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.HealthProbeBindAddress, "health-probe-address", "", ":8081", "health and readiness probe address")
+	cmd.PersistentFlags().BoolVarP(&mgrConfig.LeaderElection, "leader-elect", "", true, "elect a leader among the replicas, only the leader reports changes")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.LeaderElectionID, "leader-election-id", "", "kubewatch", "name of the leader election lease")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.LeaderElectionNamespace, "leader-election-namespace", "", "", "namespace of the leader election lease, the namespace of the pod if empty")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.MetricRules, "metric-rules", "", "", "file of rules mapping object fields to metrics")
+	cmd.PersistentFlags().DurationVarP(&mgrConfig.DiscoveryInterval, "discovery-interval", "", manager.DefaultDiscoveryInterval, "how often newly installed and removed kinds are discovered, never if 0")
+	cmd.PersistentFlags().BoolVarP(&mgrConfig.KubeWatches, "kubewatches", "", false, "reconcile KubeWatch resources into watches, only --kind is watched in addition")
+}
@@ -18,17 +18,18 @@ package cmd
 import (
 	"context"
 	"os"
-	"os/exec"
 	"reflect"
 	"runtime"
-	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/nfyxhan/kubewatch/pkg/completion"
 	"github.com/nfyxhan/kubewatch/pkg/manager"
+	"github.com/nfyxhan/kubewatch/pkg/output"
 )
 
 var mgrConfig = manager.Config{}
@@ -36,10 +37,10 @@ var mgrConfig = manager.Config{}
 func init() {
 	// watchCmd represents the watch command
 	var watchCmd = &cobra.Command{
-		// PreRun: func(cmd *cobra.Command, args []string) {
-		// 	utils.SetMgrConfig(mgrConfig)
-		// 	completion.SetMgrConfig(mgrConfig)
-		// },
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			mgrConfig.Names = args
+			return loadConfig(cmd)
+		},
 		Use:               "watch type [nameprefix]",
 		ValidArgsFunction: makeCobraFunc(cobra.ShellCompDirectiveNoFileComp, completion.NameComplitionFunc),
 		Short:             "A brief description of your command",
@@ -52,14 +53,9 @@ Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			cfg, err := mgrConfig.GetKubeConfig()
-			if err != nil {
-				panic(err)
-			}
-			sc := manager.NewSchemeClient(cfg)
-			ctx := context.Background()
-			mgrConfig.Names = args
-			mgr, err := manager.NewManager(ctx, mgrConfig, sc)
+			ctx, cancel := context.WithCancel(ctrl.SetupSignalHandler())
+			defer cancel()
+			mgr, err := manager.NewClusterManager(ctx, mgrConfig)
 			if err != nil {
 				panic(err)
 			}
@@ -67,50 +63,87 @@ to quickly create a Cobra application.`,
 			if err := mgr.Start(ctx); err != nil {
 				panic(err)
 			}
-			<-ctx.Done()
-			if err := ctx.Err(); err != nil {
-				panic(err)
+			select {
+			case <-ctx.Done():
+			case <-mgr.Done():
+				// the user quit the tui output
+				cancel()
 			}
+			// flush the webhooks and finish the journal, e.g. its gzip trailer
+			mgr.Close()
 		},
 	}
-	size := GetTtySize()
-	watchCmd.PersistentFlags().StringVarP(&mgrConfig.Namespace, "namespace", "n", "", "object namespace prefix")
-	watchCmd.PersistentFlags().StringVarP(&mgrConfig.GroupVersion, "group-version", "g", "", "group version")
-	watchCmd.PersistentFlags().StringVarP(&mgrConfig.PathPrefix, "path-prefix", "p", "", "object path prefix")
-	watchCmd.PersistentFlags().StringVarP(&mgrConfig.PathTemplate, "path-template", "t", "", "object path template")
-	watchCmd.PersistentFlags().StringVarP(&mgrConfig.Objects, "kind", "k", "", "kind")
-	watchCmd.PersistentFlags().StringVarP(&mgrConfig.ExcludeObjects, "exclude-kind", "", "", "exclude kind")
 	watchCmd.PersistentFlags().StringVarP(&mgrConfig.MetricsBindAddress, "metrics-address", "m", ":6666", "metrics address")
-	watchCmd.PersistentFlags().BoolVarP(&mgrConfig.EnableAnnotations, "enable-annotations", "a", true, "enable annotations")
-	watchCmd.PersistentFlags().BoolVarP(&mgrConfig.IgnoreMetadata, "ignore-metadate", "i", true, "ignore metadata")
-	watchCmd.PersistentFlags().BoolVarP(&mgrConfig.SliceOrdering, "slice-ordering", "", true, "slice ordering")
-	watchCmd.PersistentFlags().IntVarP(&mgrConfig.ColumnWidthMax, "column-width-max", "", size[1]/4, "column width max")
-	watchCmd.PersistentFlags().IntVarP(&mgrConfig.RowWidthMax, "row-width-max", "", size[1], "column width max")
-	watchCmd.PersistentFlags().IntVarP(&mgrConfig.MaxRows, "max-rows", "", size[0]-4, "max rows")
-	watchCmd.RegisterFlagCompletionFunc("kind", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.KindComplitionFunc))
-	watchCmd.RegisterFlagCompletionFunc("exclude-kind", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.KindComplitionFunc))
-	watchCmd.RegisterFlagCompletionFunc("namespace", makeCobraFunc(cobra.ShellCompDirectiveNoFileComp, completion.NamespaceCompletionFunc))
-	watchCmd.RegisterFlagCompletionFunc("path-prefix", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.PathPrefixComplitionFunc))
-	watchCmd.RegisterFlagCompletionFunc("group-version", makeCobraFunc(cobra.ShellCompDirectiveNoFileComp, completion.GroupVersionComplitionFunc))
+	watchCmd.PersistentFlags().StringVarP(&mgrConfig.MetricRules, "metric-rules", "", "", "file of rules mapping object fields to metrics")
+	watchCmd.PersistentFlags().StringVarP(&mgrConfig.Context, "context", "", "", "kubeconfig contexts to watch concurrently, comma separated")
+	watchCmd.PersistentFlags().BoolVarP(&mgrConfig.AllContexts, "all-contexts", "", false, "watch all kubeconfig contexts, ignores --context")
+	watchCmd.RegisterFlagCompletionFunc("context", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.ContextCompletionFunc))
+	watchCmd.PersistentFlags().StringVarP(&mgrConfig.Record, "record", "", "", "append all events to a journal file, gzip compressed if it ends with .gz")
+	watchCmd.PersistentFlags().DurationVarP(&mgrConfig.DiscoveryInterval, "discovery-interval", "", manager.DefaultDiscoveryInterval, "how often newly installed and removed kinds are discovered, never if 0")
+	addWatchFlags(watchCmd)
+	addProfileFlag(watchCmd)
 	rootCmd.AddCommand(watchCmd)
 }
 
+// addWatchFlags adds the flags selecting and rendering object changes, shared
+// by watch and replay.
+func addWatchFlags(cmd *cobra.Command) {
+	size := GetTtySize()
+	cmd.PersistentFlags().StringVarP(&mgrConfig.Namespace, "namespace", "n", "", "object namespaces, comma separated")
+	cmd.PersistentFlags().BoolVarP(&mgrConfig.AllNamespaces, "all-namespaces", "A", false, "watch objects in all namespaces, ignores --namespace")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.ExcludeNamespaces, "exclude-namespace", "", "", "exclude namespaces, comma separated")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.ExcludeNames, "exclude-name", "", "", "exclude object names, comma separated")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.MatchMode, "match", "", "", "match mode of names and namespaces, one of "+strings.Join(manager.MatchModes, "|")+", by default namespaces match exactly and names by prefix")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.GroupVersion, "group-version", "g", "", "group version of the kinds, all groups if empty")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.PathPrefix, "path-prefix", "p", "", "object path prefix")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.PathTemplate, "path-template", "t", "", "object path template")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.LabelSelector, "selector", "l", "", "label selector, supports '=', '==', '!=', 'in', 'notin' and '!key'")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.FieldSelector, "field-selector", "", "", "field selector, e.g. status.phase=Running, only metadata.name and metadata.namespace are selected by the server, other fields are matched by kubewatch")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.Objects, "kind", "k", "", "kinds, comma separated, resolved like kubectl, e.g. deploy, deployments.apps or Deployment.v1.apps")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.ExcludeObjects, "exclude-kind", "", "", "exclude kinds, comma separated")
+	cmd.PersistentFlags().BoolVarP(&mgrConfig.EnableAnnotations, "enable-annotations", "a", true, "enable annotations")
+	cmd.PersistentFlags().BoolVarP(&mgrConfig.IgnoreMetadata, "ignore-metadate", "i", true, "ignore metadata")
+	cmd.PersistentFlags().BoolVarP(&mgrConfig.SliceOrdering, "slice-ordering", "", true, "slice ordering")
+	cmd.PersistentFlags().IntVarP(&mgrConfig.ColumnWidthMax, "column-width-max", "", size[1]/4, "column width max")
+	cmd.PersistentFlags().IntVarP(&mgrConfig.RowWidthMax, "row-width-max", "", size[1], "column width max")
+	cmd.PersistentFlags().IntVarP(&mgrConfig.MaxRows, "max-rows", "", size[0]-4, "max rows")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.Output, "output", "o", output.FormatTable, "output format, one of "+strings.Join(output.Formats, "|"))
+	cmd.PersistentFlags().StringVarP(&mgrConfig.DiffFormat, "diff-format", "", manager.DiffFields, "diff format, one of "+strings.Join(manager.DiffFormats, "|"))
+	cmd.PersistentFlags().StringVarP(&mgrConfig.Scope, "scope", "", manager.ScopeAll, "changes to watch, one of "+strings.Join(manager.Scopes, "|")+", status needs a status subresource")
+	cmd.PersistentFlags().BoolVarP(&mgrConfig.MetadataOnly, "metadata-only", "", false, "watch only the metadata of the objects, e.g. labels, annotations and owner references, to save memory")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.StripFields, "strip-fields", "", "", "dotted paths removed before objects are cached, comma separated, optionally prefixed by a kind, e.g. configmaps:data")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.SummaryFields, "summary-fields", "", manager.DefaultSummaryFields, "fields shown for created and deleted objects, comma separated, none if empty")
+	cmd.PersistentFlags().StringVarP(&mgrConfig.Filter, "filter", "", "", "Starlark expression of old and new, updates are dropped if false, e.g. new.spec.replicas != old.spec.replicas")
+	cmd.PersistentFlags().StringArrayVarP(&mgrConfig.Compute, "compute", "", nil, "name=expression of obj shown with the changes and exported as metric, repeatable, e.g. ready=get(obj, \"status.readyReplicas\", 0)")
+	cmd.RegisterFlagCompletionFunc("kind", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.KindComplitionFunc))
+	cmd.RegisterFlagCompletionFunc("exclude-kind", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.KindComplitionFunc))
+	cmd.RegisterFlagCompletionFunc("namespace", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.NamespaceCompletionFunc))
+	cmd.RegisterFlagCompletionFunc("exclude-namespace", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.NamespaceCompletionFunc))
+	cmd.RegisterFlagCompletionFunc("match", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return manager.MatchModes, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("path-prefix", makeCobraFunc(cobra.ShellCompDirectiveNoSpace, completion.PathPrefixComplitionFunc))
+	cmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return output.Formats, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("diff-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return manager.DiffFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("scope", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return manager.Scopes, cobra.ShellCompDirectiveNoFileComp
+	})
+	cmd.RegisterFlagCompletionFunc("group-version", makeCobraFunc(cobra.ShellCompDirectiveNoFileComp, completion.GroupVersionComplitionFunc))
+}
+
+// GetTtySize returns the rows and columns of the terminal. The tui output
+// follows later resizes itself.
 func GetTtySize() []int {
-	cmd := exec.Command("stty", "size")
-	cmd.Stdin = os.Stdin
-	out, _ := cmd.Output()
-	s := string(out)
-	s = strings.ReplaceAll(s, "\n", "")
-	ll := strings.Split(s, " ")
-	res := make([]int, 0)
-	for _, l := range ll {
-		i, _ := strconv.Atoi(l)
-		res = append(res, i)
-	}
-	if len(res) < 2 {
-		res = append(res, 10)
+	for _, f := range []*os.File{os.Stdout, os.Stdin} {
+		if w, h, err := term.GetSize(int(f.Fd())); err == nil {
+			return []int{h, w}
+		}
 	}
-	return res[:2]
+	return []int{0, 10}
 }
 
 func makeCobraFunc(directive cobra.ShellCompDirective, f func(ctx context.Context, mgrConfig manager.Config) ([]string, error)) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -124,6 +157,10 @@ func makeCobraFunc(directive cobra.ShellCompDirective, f func(ctx context.Contex
 			ctx = context.Background()
 		}
 		fnName := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+		initConfig()
+		if err := loadConfig(cmd); err != nil {
+			log.FromContext(ctx).Error(err, "failed to load config", "cobra func", fnName)
+		}
 		logger := log.FromContext(ctx).WithValues("cobra func", fnName, "config", mgrConfig)
 		res, err := f(ctx, mgrConfig)
 		if err != nil {
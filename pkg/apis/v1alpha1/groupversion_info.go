@@ -0,0 +1,18 @@
+// Package v1alpha1 contains the KubeWatch API, watches configured by
+// resources in the cluster and reconciled by kubewatch serve.
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+const GroupName = "kubewatch.nfyxhan.io"
+
+var (
+	GroupVersion = schema.GroupVersion{Group: GroupName, Version: "v1alpha1"}
+
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	AddToScheme = SchemeBuilder.AddToScheme
+)
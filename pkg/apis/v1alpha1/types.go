@@ -0,0 +1,106 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionReady is true while the watch of a KubeWatch is running.
+	ConditionReady = "Ready"
+
+	ReasonStarted = "Started"
+	ReasonFailed  = "Failed"
+)
+
+// KubeWatchSpec selects the objects to watch like the flags of kubewatch
+// watch, and the webhooks their changes are sent to.
+type KubeWatchSpec struct {
+	// GroupVersion of the watched kinds, e.g. apps/v1, v1 for the core group,
+	// all groups if empty.
+	GroupVersion string `json:"groupVersion,omitempty"`
+	// Kinds are resolved like kubectl, e.g. deploy, deployments.apps or
+	// Deployment.v1.apps, all kinds of the group version if empty.
+	Kinds        []string `json:"kinds,omitempty"`
+	ExcludeKinds []string `json:"excludeKinds,omitempty"`
+	// Namespaces can only be the namespace of the KubeWatch, which is also
+	// watched if empty.
+	Namespaces        []string `json:"namespaces,omitempty"`
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	Names             []string `json:"names,omitempty"`
+	ExcludeNames      []string `json:"excludeNames,omitempty"`
+	// MatchMode is one of prefix, exact, glob and regex. If empty, names
+	// match by prefix and excluded namespaces exactly.
+	MatchMode     string `json:"matchMode,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	FieldSelector string `json:"fieldSelector,omitempty"`
+	PathPrefix    string `json:"pathPrefix,omitempty"`
+	PathTemplate  string `json:"pathTemplate,omitempty"`
+	// IgnoreMetadata and EnableAnnotations default to the flags of serve.
+	IgnoreMetadata    *bool  `json:"ignoreMetadata,omitempty"`
+	EnableAnnotations *bool  `json:"enableAnnotations,omitempty"`
+	DiffFormat        string `json:"diffFormat,omitempty"`
+	// Scope is one of all, status and spec, all if empty.
+	Scope string `json:"scope,omitempty"`
+	// MetadataOnly defaults to the flag of serve, StripFields are dotted
+	// paths, optionally prefixed by a kind and a colon.
+	MetadataOnly *bool    `json:"metadataOnly,omitempty"`
+	StripFields  []string `json:"stripFields,omitempty"`
+	// Filter is a Starlark expression of old and new dropping updates,
+	// Compute are name=expression pairs of obj.
+	Filter  string   `json:"filter,omitempty"`
+	Compute []string `json:"compute,omitempty"`
+	// Webhooks the changes are sent to, in addition to the output of serve.
+	// They get the watched objects, which is why the kinds are limited to
+	// those the default ServiceAccount of the namespace may read.
+	Webhooks []Webhook `json:"webhooks,omitempty"`
+}
+
+// Webhook is a webhook sink, see the webhooks of the config file.
+type Webhook struct {
+	Name    string            `json:"name,omitempty"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Paths are regular expressions, only changes of matching paths are
+	// sent. All changes are sent if empty.
+	Paths         []string         `json:"paths,omitempty"`
+	Template      string           `json:"template,omitempty"`
+	BatchSize     int              `json:"batchSize,omitempty"`
+	BatchInterval *metav1.Duration `json:"batchInterval,omitempty"`
+	MaxRetries    int              `json:"maxRetries,omitempty"`
+	Backoff       *metav1.Duration `json:"backoff,omitempty"`
+	Timeout       *metav1.Duration `json:"timeout,omitempty"`
+	QueueSize     int              `json:"queueSize,omitempty"`
+}
+
+// KubeWatchStatus is the state of the watch of a KubeWatch.
+type KubeWatchStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Kinds are the watched kinds.
+	Kinds      []string           `json:"kinds,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// KubeWatch is a watch configured in the cluster. kubewatch reads with its
+// own role and the webhooks post the objects anywhere, so a KubeWatch only
+// watches the kinds the default ServiceAccount of its namespace may list and
+// watch there, its creator can't read more than the workloads of the
+// namespace can.
+type KubeWatch struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KubeWatchSpec   `json:"spec,omitempty"`
+	Status KubeWatchStatus `json:"status,omitempty"`
+}
+
+// KubeWatchList is a list of KubeWatch.
+type KubeWatchList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KubeWatch `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KubeWatch{}, &KubeWatchList{})
+}
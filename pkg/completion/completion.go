@@ -19,11 +19,35 @@ func NamespaceCompletionFunc(ctx context.Context, config manager.Config) ([]stri
 	if err != nil {
 		return nil, err
 	}
-	result := make([]string, 0)
+	names := make([]string, 0, len(res))
 	for _, r := range res {
-		result = append(result, r.Name)
+		names = append(names, r.Name)
 	}
-	return result, nil
+	return completeList(config.ToComplete, names), nil
+}
+
+func ContextCompletionFunc(ctx context.Context, config manager.Config) ([]string, error) {
+	contexts, err := manager.ListContexts()
+	if err != nil {
+		return nil, err
+	}
+	return completeList(config.ToComplete, contexts), nil
+}
+
+// completeList completes the last item of a comma separated list, skipping
+// the items already in the list.
+func completeList(toComplete string, names []string) []string {
+	ss := strings.Split(toComplete, ",")
+	l := len(ss)
+	prefix, s := ss[:l-1], ss[l-1]
+	result := make([]string, 0)
+	for _, name := range names {
+		if !strings.HasPrefix(name, s) || slices.Contains(prefix, name) {
+			continue
+		}
+		result = append(result, strings.Join(append(append([]string{}, prefix...), name), ","))
+	}
+	return result
 }
 
 func GroupVersionComplitionFunc(ctx context.Context, config manager.Config) ([]string, error) {
@@ -78,31 +102,21 @@ func KindComplitionFunc(ctx context.Context, config manager.Config) ([]string, e
 		return nil, err
 	}
 	cli := manager.NewSchemeClient(cfg)
-	result := make([]string, 0)
-	res, err := cli.GetObjectMap(ctx, config.GroupVersion)
+	rs, err := cli.GetResources(ctx, config.GroupVersion)
 	if err != nil {
 		return nil, err
 	}
-	o := config.ToComplete
-	ss := strings.Split(o, ",")
-	l := len(ss)
-	s := ss[l-1]
-	for k := range res {
-		// if len(ss) > 1 {
-		// 	return []string{o + "prefix", "app", "as"}, nil
-		// } else {
-		// 	return []string{o + "prefix", "am", "amh"}, nil
-		// }
-		if o != "" && s != "" && !strings.HasPrefix(k, s) {
-			continue
-		}
-		if slices.Contains(ss, k) {
-			continue
+	// short names within a group version, qualified names across groups
+	names := make([]string, 0)
+	for _, r := range rs {
+		if config.GroupVersion != "" {
+			names = append(names, r.Name)
+			names = append(names, r.ShortNames...)
+		} else {
+			names = append(names, r.QualifiedName())
 		}
-		result = append(result, strings.Join(append(ss[:l-1], k), ","))
 	}
-	return result, nil
-
+	return completeList(config.ToComplete, names), nil
 }
 
 func NameComplitionFunc(ctx context.Context, config manager.Config) ([]string, error) {
@@ -0,0 +1,175 @@
+package journal
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	TypeCreate = "create"
+	TypeUpdate = "update"
+	TypeDelete = "delete"
+)
+
+// Entry is one recorded watch event. Old is only set for update and delete
+// events, New only for create and update events.
+type Entry struct {
+	Time     time.Time                  `json:"time"`
+	Cluster  string                     `json:"cluster,omitempty"`
+	Type     string                     `json:"type"`
+	Resource *Resource                  `json:"resource,omitempty"`
+	Old      *unstructured.Unstructured `json:"old,omitempty"`
+	New      *unstructured.Unstructured `json:"new,omitempty"`
+}
+
+// Resource are the names of the resource of the object of an entry, so a
+// replay resolves kinds like the watch, e.g. by short name. Journals recorded
+// before have none.
+type Resource struct {
+	// Name is the plural resource name, e.g. deployments.
+	Name         string   `json:"name"`
+	SingularName string   `json:"singularName,omitempty"`
+	ShortNames   []string `json:"shortNames,omitempty"`
+}
+
+// Object returns the most recent snapshot of the object of the entry.
+func (e Entry) Object() *unstructured.Unstructured {
+	if e.New != nil {
+		return e.New
+	}
+	return e.Old
+}
+
+// Recorder appends entries to a journal file, one JSON document per line.
+// Files ending with .gz are gzip compressed.
+type Recorder struct {
+	sync.Mutex
+	f   *os.File
+	gz  *gzip.Writer
+	enc *json.Encoder
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{
+		f: f,
+	}
+	var w io.Writer = f
+	if strings.HasSuffix(path, ".gz") {
+		r.gz = gzip.NewWriter(f)
+		w = r.gz
+	}
+	r.enc = json.NewEncoder(w)
+	return r, nil
+}
+
+// Record appends an event of a cluster, which is empty when watching a
+// single cluster, of an object of the resource.
+func (r *Recorder) Record(t time.Time, cluster, eventType string, res *Resource, objOld, objNew client.Object) error {
+	e := Entry{
+		Time:     t,
+		Cluster:  cluster,
+		Type:     eventType,
+		Resource: res,
+	}
+	var err error
+	if e.Old, err = toUnstructured(objOld); err != nil {
+		return err
+	}
+	if e.New, err = toUnstructured(objNew); err != nil {
+		return err
+	}
+	r.Lock()
+	defer r.Unlock()
+	if err := r.enc.Encode(e); err != nil {
+		return err
+	}
+	if r.gz != nil {
+		return r.gz.Flush()
+	}
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	r.Lock()
+	defer r.Unlock()
+	if r.gz != nil {
+		if err := r.gz.Close(); err != nil {
+			return err
+		}
+	}
+	return r.f.Close()
+}
+
+func toUnstructured(obj client.Object) (*unstructured.Unstructured, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	return u, nil
+}
+
+// Reader reads entries from a journal file written by a Recorder.
+type Reader struct {
+	f   *os.File
+	dec *json.Decoder
+}
+
+func NewReader(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r = gz
+	}
+	return &Reader{
+		f:   f,
+		dec: json.NewDecoder(r),
+	}, nil
+}
+
+// Next returns the next entry, or io.EOF at the end of the journal. The end
+// of a journal whose recorder wasn't closed, e.g. a gzip file without its
+// trailer or a partly written entry, is read as io.EOF too.
+func (r *Reader) Next() (*Entry, error) {
+	e := &Entry{}
+	if err := r.dec.Decode(e); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func (r *Reader) Close() error {
+	return r.f.Close()
+}
@@ -0,0 +1,97 @@
+package journal
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecorder(t *testing.T) {
+	for _, name := range []string{"journal.ndjson", "journal.ndjson.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+			pod := &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "default"},
+			}
+			// two recorders append to the same file
+			for _, eventType := range []string{TypeCreate, TypeDelete} {
+				r, err := NewRecorder(path)
+				if err != nil {
+					t.Fatalf("NewRecorder() error = %v", err)
+				}
+				switch eventType {
+				case TypeCreate:
+					err = r.Record(now, "", eventType, nil, nil, pod)
+				case TypeDelete:
+					err = r.Record(now, "", eventType, nil, pod, nil)
+				}
+				if err != nil {
+					t.Fatalf("Record() error = %v", err)
+				}
+				if err := r.Close(); err != nil {
+					t.Fatalf("Close() error = %v", err)
+				}
+			}
+			reader, err := NewReader(path)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			defer reader.Close()
+			var got []string
+			for {
+				e, err := reader.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next() error = %v", err)
+				}
+				if !e.Time.Equal(now) || e.Object().GetName() != "podinfo" || e.Object().GetKind() != "Pod" {
+					t.Errorf("Next() got = %+v", e)
+				}
+				got = append(got, e.Type)
+			}
+			if len(got) != 2 || got[0] != TypeCreate || got[1] != TypeDelete {
+				t.Errorf("Next() got types = %v", got)
+			}
+		})
+	}
+}
+
+// TestRecorderNotClosed reads the journal of a watch which was interrupted
+// before its recorder was closed.
+func TestRecorderNotClosed(t *testing.T) {
+	for _, name := range []string{"journal.ndjson", "journal.ndjson.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			pod := &corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Name: "podinfo", Namespace: "default"},
+			}
+			r, err := NewRecorder(path)
+			if err != nil {
+				t.Fatalf("NewRecorder() error = %v", err)
+			}
+			if err := r.Record(time.Now(), "", TypeCreate, nil, nil, pod); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+			reader, err := NewReader(path)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			defer reader.Close()
+			if e, err := reader.Next(); err != nil || e.Type != TypeCreate {
+				t.Fatalf("Next() = %+v, %v", e, err)
+			}
+			if _, err := reader.Next(); err != io.EOF {
+				t.Errorf("Next() error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
@@ -13,6 +13,14 @@ import (
 type SchemeObject struct {
 	Name       string
 	ShortNames []string
+	// Resource is the plural resource name, e.g. for RBAC rules.
+	Resource string
+	// Verbs are the supported verbs, unknown if empty.
+	Verbs []string
+	// Subresources are the names of the subresources, e.g. status.
+	Subresources []string
+	// Namespaced is false for cluster scoped resources, e.g. nodes.
+	Namespaced bool
 	client.Object
 	client.ObjectList
 }
@@ -22,8 +30,13 @@ type SchemeClient interface {
 	GetRestConfig() *rest.Config
 	ListApiGroups(ctx context.Context) ([]metav1.APIGroup, error)
 	ListApiResources(ctx context.Context, group string) (*metav1.APIResourceList, error)
-	GetObjectMap(ctx context.Context, group string) (map[string]SchemeObject, error)
+	GetResources(ctx context.Context, groupVersion string) (Resources, error)
 	ListNamespace(ctx context.Context) ([]corev1.Namespace, error)
+	// Invalidate drops the cached discovery.
+	Invalidate()
+	// CanWatch reports whether the ServiceAccount may list and watch the kind
+	// in its namespace.
+	CanWatch(ctx context.Context, namespace, serviceAccount string, o SchemeObject) (bool, error)
 }
 
 func NewSchemeClient(cfg *rest.Config) SchemeClient {
@@ -37,4 +50,11 @@ type ObjectClient interface {
 	GetObjectsKind(ctx context.Context, groupVersion string, objects string) ([]SchemeObject, error)
 	ListObjects(ctx context.Context, groupVersion string, objects string, namespace string) ([]string, error)
 	ListObjectPathPrefix(ctx context.Context, groupVersion, kind, pathPrefix string) ([]string, error)
+	// AddKinds and RemoveKinds change the watched kinds while running.
+	AddKinds(ctx context.Context, kinds ...string) error
+	RemoveKinds(ctx context.Context, kinds ...string) error
+	WatchedKinds() []string
+	// Done is closed when the user quits the interactive output.
+	Done() <-chan struct{}
+	Close()
 }
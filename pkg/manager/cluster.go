@@ -0,0 +1,133 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/nfyxhan/kubewatch/pkg/utils"
+)
+
+// ListContexts returns the names of all contexts of the kubeconfig.
+func ListContexts() ([]string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = utils.Kubeconfig
+	raw, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, nil).RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	contexts := make([]string, 0, len(raw.Contexts))
+	for name := range raw.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+// GetContexts returns the kubeconfig contexts to watch, none for the current
+// context.
+func (c Config) GetContexts() ([]string, error) {
+	if c.AllContexts {
+		return ListContexts()
+	}
+	return splitList(c.Context), nil
+}
+
+// NewClusterManager starts one manager per context of the config, merging
+// their events into a single output tagged with the context name. Without
+// contexts it watches the current context like NewManager.
+func NewClusterManager(ctx context.Context, config Config) (ObjectClient, error) {
+	contexts, err := config.GetContexts()
+	if err != nil {
+		return nil, err
+	}
+	if len(contexts) == 0 {
+		return NewManager(ctx, config, nil)
+	}
+	managers := make(clusterManagers, 0, len(contexts))
+	for i, name := range contexts {
+		c := config
+		c.Context = name
+		c.AllContexts = false
+		if i > 0 {
+			// all managers share the global metrics registry and the probes
+			// of the first one
+			c.MetricsBindAddress = "0"
+			c.HealthProbeBindAddress = "0"
+		}
+		m, err := NewManager(ctx, c, nil)
+		if err != nil {
+			return nil, fmt.Errorf("context %s: %v", name, err)
+		}
+		managers = append(managers, m.(*manager))
+	}
+	return managers, nil
+}
+
+type clusterManagers []*manager
+
+// Start opens the shared outputs and starts the managers of all clusters.
+func (cm clusterManagers) Start(ctx context.Context) error {
+	out, err := newOutputs(ctx, cm[0].config)
+	if err != nil {
+		return err
+	}
+	for _, m := range cm {
+		m.outputs = out
+	}
+	for _, m := range cm {
+		if err := m.Start(ctx); err != nil {
+			return fmt.Errorf("context %s: %v", m.cluster, err)
+		}
+	}
+	return nil
+}
+
+// Close stops the watches of all clusters and flushes the shared outputs.
+func (cm clusterManagers) Close() {
+	for _, m := range cm {
+		m.Close()
+	}
+}
+
+func (cm clusterManagers) GetObjectsKind(ctx context.Context, groupVersion string, objects string) ([]SchemeObject, error) {
+	return cm[0].GetObjectsKind(ctx, groupVersion, objects)
+}
+
+func (cm clusterManagers) ListObjects(ctx context.Context, groupVersion string, objects string, namespace string) ([]string, error) {
+	return cm[0].ListObjects(ctx, groupVersion, objects, namespace)
+}
+
+func (cm clusterManagers) ListObjectPathPrefix(ctx context.Context, groupVersion, kind, pathPrefix string) ([]string, error) {
+	return cm[0].ListObjectPathPrefix(ctx, groupVersion, kind, pathPrefix)
+}
+
+// AddKinds adds the kinds to the watches of all clusters.
+func (cm clusterManagers) AddKinds(ctx context.Context, kinds ...string) error {
+	for _, m := range cm {
+		if err := m.AddKinds(ctx, kinds...); err != nil {
+			return fmt.Errorf("context %s: %v", m.cluster, err)
+		}
+	}
+	return nil
+}
+
+// RemoveKinds removes the kinds from the watches of all clusters.
+func (cm clusterManagers) RemoveKinds(ctx context.Context, kinds ...string) error {
+	for _, m := range cm {
+		if err := m.RemoveKinds(ctx, kinds...); err != nil {
+			return fmt.Errorf("context %s: %v", m.cluster, err)
+		}
+	}
+	return nil
+}
+
+func (cm clusterManagers) WatchedKinds() []string {
+	return cm[0].WatchedKinds()
+}
+
+func (cm clusterManagers) Done() <-chan struct{} {
+	return cm[0].Done()
+}
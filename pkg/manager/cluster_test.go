@@ -0,0 +1,36 @@
+package manager
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nfyxhan/kubewatch/pkg/journal"
+)
+
+func TestClusterManagersClose(t *testing.T) {
+	recorder, err := journal.NewRecorder(filepath.Join(t.TempDir(), "events.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &outputs{recorder: recorder}
+	var cm clusterManagers
+	stopped := make(map[string]bool)
+	for _, name := range []string{"staging", "production"} {
+		m, err := newManager(Config{Context: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := name
+		m.outputs = out
+		m.kindWatches["pods"] = &kindWatch{cancel: func() { stopped[name] = true }}
+		cm = append(cm, m)
+	}
+	cm.Close()
+	if !stopped["staging"] || !stopped["production"] {
+		t.Errorf("stopped kinds = %v, want those of all clusters", stopped)
+	}
+	if err := recorder.Record(time.Now(), "staging", journal.TypeCreate, nil, nil, nil); err == nil {
+		t.Errorf("journal not closed")
+	}
+}
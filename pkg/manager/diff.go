@@ -0,0 +1,308 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/r3labs/diff/v3"
+	"gomodules.xyz/jsonpatch/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+const (
+	DiffFields     = "fields"
+	DiffUnified    = "unified"
+	DiffPatch      = "patch"
+	DiffSideBySide = "side-by-side"
+)
+
+var DiffFormats = []string{
+	DiffFields,
+	DiffUnified,
+	DiffPatch,
+	DiffSideBySide,
+}
+
+// filterPath applies the ignored paths, path prefix and path template of the
+// config to a changed path, returning the path to print.
+func (c Config) filterPath(segments []string) (string, bool) {
+	path := strings.Join(segments, Split)
+	ignored := ignorePath
+	if !c.IgnoreMetadata {
+		ignored = nil
+	}
+	if _, ok := ignored[path]; ok {
+		return "", false
+	}
+	if !c.inScope(segments) {
+		return "", false
+	}
+	p := strings.ToLower(path)
+	for k := range ignored {
+		if strings.HasPrefix(p, strings.ToLower(k)) {
+			return "", false
+		}
+	}
+	if !c.EnableAnnotations {
+		for _, k := range AnnotationsPaths {
+			if strings.HasPrefix(p, strings.ToLower(k)) {
+				return "", false
+			}
+		}
+	}
+	if pathPrefix := c.PathPrefix; pathPrefix != "" {
+		if !strings.HasPrefix(p, strings.ToLower(pathPrefix)) {
+			return "", false
+		}
+		path = path[len(pathPrefix):]
+	}
+	path = strings.ReplaceAll(path, Split, SplitPrint)
+	if t := c.PathTemplate; t != "" {
+		if ok, _ := regexp.MatchString(t, path); !ok {
+			return "", false
+		}
+	}
+	path = strings.TrimPrefix(path, "Object/")
+	return path, true
+}
+
+// diffChanges returns the filtered changes between two objects, computed
+// field by field or as RFC 6902 JSON Patch operations.
+func diffChanges(objNew, objOld client.Object, config Config) ([]output.Change, error) {
+	if config.DiffFormat == DiffPatch {
+		return patchChanges(objNew, objOld, config)
+	}
+	changeLogs, err := diff.Diff(objOld, objNew, diff.SliceOrdering(config.SliceOrdering))
+	if err != nil {
+		return nil, err
+	}
+	var changes []output.Change
+	for _, changeLog := range changeLogs {
+		path, ok := config.filterPath(changeLog.Path)
+		if !ok {
+			continue
+		}
+		changes = append(changes, output.Change{
+			Path: path,
+			From: changeLog.From,
+			To:   changeLog.To,
+			Op:   changeLog.Type,
+		})
+	}
+	return changes, nil
+}
+
+func patchChanges(objNew, objOld client.Object, config Config) ([]output.Change, error) {
+	contentOld, err := objectContent(objOld)
+	if err != nil {
+		return nil, err
+	}
+	contentNew, err := objectContent(objNew)
+	if err != nil {
+		return nil, err
+	}
+	a, err := json.Marshal(contentOld)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(contentNew)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := jsonpatch.CreatePatch(a, b)
+	if err != nil {
+		return nil, err
+	}
+	var changes []output.Change
+	for _, op := range ops {
+		segments := pointerSegments(op.Path)
+		filterSegments := segments
+		if _, ok := objNew.(*unstructured.Unstructured); ok {
+			// keep the paths in line with the field diff of unstructured objects
+			filterSegments = append([]string{"Object"}, segments...)
+		}
+		if _, ok := config.filterPath(filterSegments); !ok {
+			continue
+		}
+		var from interface{}
+		if op.Operation != "add" {
+			from = lookupPath(contentOld, segments)
+		}
+		changes = append(changes, output.Change{
+			Path: op.Path,
+			From: from,
+			To:   op.Value,
+			Op:   op.Operation,
+		})
+	}
+	return changes, nil
+}
+
+// textDiff renders the filtered objects as YAML and returns their unified or
+// side-by-side diff. Computed values are appended under a computed key.
+func textDiff(objNew, objOld client.Object, config Config, computed []output.Change) (string, error) {
+	a, err := filteredYAML(objOld, config)
+	if err != nil {
+		return "", err
+	}
+	b, err := filteredYAML(objNew, config)
+	if err != nil {
+		return "", err
+	}
+	if len(computed) > 0 {
+		from, to := map[string]interface{}{}, map[string]interface{}{}
+		for _, c := range computed {
+			from[c.Path], to[c.Path] = c.From, c.To
+		}
+		ca, err := yaml.Marshal(map[string]interface{}{"computed": from})
+		if err != nil {
+			return "", err
+		}
+		cb, err := yaml.Marshal(map[string]interface{}{"computed": to})
+		if err != nil {
+			return "", err
+		}
+		a, b = a+string(ca), b+string(cb)
+	}
+	color := config.Output == "" || config.Output == output.FormatTable || config.Output == output.FormatTUI
+	if config.DiffFormat == DiffSideBySide {
+		return output.SideBySide(a, b, config.RowWidthMax, color), nil
+	}
+	key := fmt.Sprintf("%s/%s", strings.ToLower(objNew.GetObjectKind().GroupVersionKind().Kind), objNew.GetName())
+	if ns := objNew.GetNamespace(); ns != "" {
+		key = ns + "/" + key
+	}
+	return output.UnifiedDiff(a, b, "a/"+key, "b/"+key, color)
+}
+
+// filteredYAML renders the fields of an object that pass the path filter of
+// the config as YAML, rooted at the path prefix.
+func filteredYAML(obj client.Object, config Config) (string, error) {
+	content, err := objectContent(obj)
+	if err != nil {
+		return "", err
+	}
+	var prefix []string
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		// keep the paths in line with the field diff of unstructured objects
+		prefix = []string{"Object"}
+	}
+	v, ok := filterFields(content, prefix, config)
+	if ok && config.PathPrefix != "" {
+		v = lookupPathFold(v, contentPath(obj, config.PathPrefix))
+	}
+	if !ok || v == nil {
+		return "", nil
+	}
+	b, err := yaml.Marshal(v)
+	return string(b), err
+}
+
+// filterFields returns a copy of v with the fields whose paths don't pass
+// filterPath removed, and false if none is left.
+func filterFields(v interface{}, segments []string, config Config) (interface{}, bool) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		if len(vv) > 0 {
+			m := make(map[string]interface{}, len(vv))
+			for k, f := range vv {
+				if f, ok := filterFields(f, append(segments[:len(segments):len(segments)], k), config); ok {
+					m[k] = f
+				}
+			}
+			return m, len(m) > 0
+		}
+	case []interface{}:
+		if len(vv) > 0 {
+			var l []interface{}
+			for i, f := range vv {
+				if f, ok := filterFields(f, append(segments[:len(segments):len(segments)], strconv.Itoa(i)), config); ok {
+					l = append(l, f)
+				}
+			}
+			return l, len(l) > 0
+		}
+	}
+	_, ok := config.filterPath(segments)
+	return v, ok
+}
+
+// contentPath converts a path of the field diff to a path of the object
+// content.
+func contentPath(obj client.Object, path string) []string {
+	segments := splitList(path)
+	if _, ok := obj.(*unstructured.Unstructured); ok && len(segments) > 0 && segments[0] == "Object" {
+		segments = segments[1:]
+	}
+	return segments
+}
+
+func objectContent(obj client.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+func pointerSegments(pointer string) []string {
+	segments := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, s := range segments {
+		segments[i] = strings.ReplaceAll(strings.ReplaceAll(s, "~1", "/"), "~0", "~")
+	}
+	return segments
+}
+
+// pointerPath is the inverse of pointerSegments.
+func pointerPath(segments []string) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+func lookupPath(v interface{}, segments []string) interface{} {
+	for _, s := range segments {
+		switch vv := v.(type) {
+		case map[string]interface{}:
+			v = vv[s]
+		case []interface{}:
+			i, err := strconv.Atoi(s)
+			if err != nil || i < 0 || i >= len(vv) {
+				return nil
+			}
+			v = vv[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+// lookupPathFold is lookupPath with case insensitive keys, as path prefixes
+// are matched case insensitive.
+func lookupPathFold(v interface{}, segments []string) interface{} {
+	for _, s := range segments {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			v = lookupPath(v, []string{s})
+			continue
+		}
+		v = nil
+		for k, vv := range m {
+			if strings.EqualFold(k, s) {
+				v = vv
+				break
+			}
+		}
+	}
+	return v
+}
@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+func diffDeployment(replicas int64, image string, annotations map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":            "podinfo",
+			"namespace":       "default",
+			"resourceVersion": image,
+			"annotations":     annotations,
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "podinfo", "image": image},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func TestPatchChanges(t *testing.T) {
+	objOld := diffDeployment(1, "podinfo:1", map[string]interface{}{"a": "1"})
+	objNew := diffDeployment(2, "podinfo:2", map[string]interface{}{"a": "2", "b": "1"})
+	tests := []struct {
+		name   string
+		config Config
+		want   []output.Change
+	}{
+		{
+			name:   "ignore metadata",
+			config: Config{DiffFormat: DiffPatch, IgnoreMetadata: true},
+			want: []output.Change{
+				{Path: "/spec/replicas", From: int64(1), To: float64(2), Op: "replace"},
+				{Path: "/spec/template/spec/containers/0/image", From: "podinfo:1", To: "podinfo:2", Op: "replace"},
+			},
+		},
+		{
+			name:   "annotations",
+			config: Config{DiffFormat: DiffPatch, IgnoreMetadata: true, EnableAnnotations: true, PathPrefix: "Object,metadata"},
+			want: []output.Change{
+				{Path: "/metadata/annotations/a", From: "1", To: "2", Op: "replace"},
+				{Path: "/metadata/annotations/b", To: "1", Op: "add"},
+			},
+		},
+		{
+			name:   "path prefix",
+			config: Config{DiffFormat: DiffPatch, IgnoreMetadata: true, PathPrefix: "Object,spec,template"},
+			want: []output.Change{
+				{Path: "/spec/template/spec/containers/0/image", From: "podinfo:1", To: "podinfo:2", Op: "replace"},
+			},
+		},
+		{
+			name:   "path template",
+			config: Config{DiffFormat: DiffPatch, IgnoreMetadata: true, PathTemplate: "replicas$"},
+			want: []output.Change{
+				{Path: "/spec/replicas", From: int64(1), To: float64(2), Op: "replace"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffChanges(objNew, objOld, tt.config)
+			if err != nil {
+				t.Fatalf("diffChanges() error = %v", err)
+			}
+			// the operations of map fields come in random order
+			sort.Slice(got, func(i, j int) bool { return got[i].Path < got[j].Path })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffChanges() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextDiff(t *testing.T) {
+	objOld := diffDeployment(1, "podinfo:1", nil)
+	objNew := diffDeployment(2, "podinfo:1", nil)
+	tests := []struct {
+		name     string
+		config   Config
+		computed []output.Change
+		want     string
+	}{
+		{
+			name:   "unified",
+			config: Config{DiffFormat: DiffUnified, IgnoreMetadata: true, Output: output.FormatNDJSON, PathPrefix: "Object,spec"},
+			want:   "--- a/default/deployment/podinfo\n+++ b/default/deployment/podinfo\n@@ -1,4 +1,4 @@\n-replicas: 1\n+replicas: 2\n template:\n   spec:\n     containers:\n",
+		},
+		{
+			name:   "side by side",
+			config: Config{DiffFormat: DiffSideBySide, IgnoreMetadata: true, Output: output.FormatNDJSON, PathPrefix: "Object,spec", RowWidthMax: 33},
+			want:   "replicas: 1     | replicas: 2\ntemplate:         template:\n  spec:             spec:\n    containers:       containers:\n",
+		},
+		{
+			name:   "unchanged prefix",
+			config: Config{DiffFormat: DiffUnified, IgnoreMetadata: true, Output: output.FormatNDJSON, PathPrefix: "Object,spec,template"},
+			want:   "",
+		},
+		{
+			name:   "path template",
+			config: Config{DiffFormat: DiffUnified, IgnoreMetadata: true, Output: output.FormatNDJSON, PathTemplate: "replicas$"},
+			want:   "--- a/default/deployment/podinfo\n+++ b/default/deployment/podinfo\n@@ -1,2 +1,2 @@\n spec:\n-  replicas: 1\n+  replicas: 2\n",
+		},
+		{
+			name:     "computed",
+			config:   Config{DiffFormat: DiffUnified, IgnoreMetadata: true, Output: output.FormatNDJSON, PathTemplate: "replicas$"},
+			computed: []output.Change{{Path: "ready", From: false, To: true, Op: output.OpComputed}},
+			want:     "--- a/default/deployment/podinfo\n+++ b/default/deployment/podinfo\n@@ -1,4 +1,4 @@\n spec:\n-  replicas: 1\n+  replicas: 2\n computed:\n-  ready: false\n+  ready: true\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := textDiff(objNew, objOld, tt.config, tt.computed)
+			if err != nil {
+				t.Fatalf("textDiff() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("textDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// DefaultSummaryFields are the fields shown for created and deleted objects.
+const DefaultSummaryFields = "spec.replicas,spec.nodeName,status.phase"
+
+// writeObjectEvent writes a created or deleted object as a single change
+// with a summary of the object. Deleted objects also get a reason.
+func (m *manager) writeObjectEvent(t time.Time, op string, obj client.Object, config Config) {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	e := output.Event{
+		Time:      t,
+		Cluster:   m.cluster,
+		Watch:     m.watch,
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}
+	summary, ok := hooksFor(obj).Summarize(obj)
+	if !ok {
+		summary = objectSummary(obj, splitList(config.SummaryFields))
+	}
+	if computed := m.computedSummary(obj); computed != "" {
+		summary = strings.TrimSpace(summary + " " + computed)
+	}
+	switch op {
+	case output.OpCreated:
+		e.New = obj
+		e.Changes = []output.Change{{To: summary, Op: op}}
+	case output.OpDeleted:
+		e.Old = obj
+		e.Changes = []output.Change{{From: summary, To: deletionReason(obj), Op: op}}
+	}
+	if err := m.writer.Write(e); err != nil {
+		m.log(obj).Error(err, "failed to write event")
+	}
+}
+
+// isInitialObject reports whether a created object already existed when the
+// watch started, the informer reports those as created too.
+func (m *manager) isInitialObject(obj client.Object) bool {
+	if m.started.IsZero() {
+		return false
+	}
+	// creation timestamps have a resolution of seconds
+	return obj.GetCreationTimestamp().Time.Before(m.started.Truncate(time.Second))
+}
+
+// objectSummary returns the values of the given dotted paths as key=value
+// pairs, keyed by the last path segment.
+func objectSummary(obj client.Object, fields []string) string {
+	content, err := objectContent(obj)
+	if err != nil {
+		return ""
+	}
+	var summary []string
+	for _, f := range fields {
+		segments := strings.Split(f, ".")
+		v := lookupPath(content, segments)
+		if v == nil {
+			continue
+		}
+		summary = append(summary, fmt.Sprintf("%s=%v", segments[len(segments)-1], v))
+	}
+	return strings.Join(summary, " ")
+}
+
+// deletionReason guesses why an object was deleted from its status reason,
+// controlling owner and pending finalizers.
+func deletionReason(obj client.Object) string {
+	var reasons []string
+	if content, err := objectContent(obj); err == nil {
+		if reason, ok := lookupPath(content, []string{"status", "reason"}).(string); ok && reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Controller != nil && *ref.Controller {
+			reasons = append(reasons, fmt.Sprintf("owned by %s/%s", ref.Kind, ref.Name))
+			break
+		}
+	}
+	if finalizers := obj.GetFinalizers(); len(finalizers) > 0 {
+		reasons = append(reasons, "finalizers "+strings.Join(finalizers, ","))
+	}
+	if len(reasons) == 0 {
+		return "deleted"
+	}
+	return strings.Join(reasons, ", ")
+}
@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDeletionReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		object map[string]interface{}
+		want   string
+	}{
+		{"none", map[string]interface{}{}, "deleted"},
+		{"evicted", map[string]interface{}{
+			"status": map[string]interface{}{"reason": "Evicted"},
+		}, "Evicted"},
+		{"owner and finalizers", map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"ownerReferences": []interface{}{
+					map[string]interface{}{"kind": "ReplicaSet", "name": "web-1", "controller": true},
+				},
+				"finalizers": []interface{}{"example.com/cleanup"},
+			},
+		}, "owned by ReplicaSet/web-1, finalizers example.com/cleanup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: tt.object}
+			if got := deletionReason(obj); got != tt.want {
+				t.Errorf("deletionReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectSummary(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec":   map[string]interface{}{"replicas": int64(2)},
+		"status": map[string]interface{}{"phase": "Running"},
+	}}
+	got := objectSummary(obj, splitList(DefaultSummaryFields))
+	if want := "replicas=2 phase=Running"; got != want {
+		t.Errorf("objectSummary() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,386 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/nfyxhan/kubewatch/pkg/journal"
+)
+
+// DefaultDiscoveryInterval is how often the watched kinds are refreshed from
+// the discovery of the group version.
+const DefaultDiscoveryInterval = time.Minute
+
+// kindWatch is the informer of one watched kind. It has its own cache, so
+// it can be stopped when the kind is removed.
+type kindWatch struct {
+	cancel context.CancelFunc
+	// synced is set once the cache is synced, guarded by the manager lock.
+	synced bool
+}
+
+// kinds are the kinds added and removed at runtime, overriding the kinds of
+// the config. Its lock serializes the syncs of the watched kinds.
+type kinds struct {
+	sync.Mutex
+	added   map[string]struct{}
+	removed map[string]struct{}
+}
+
+// runKinds watches the selected kinds and follows the discovery of the group
+// version until ctx is done. It is run by the controller manager, so only
+// the leader watches.
+func (m *manager) runKinds(ctx context.Context) error {
+	m.Lock()
+	objects := m.objects
+	m.Unlock()
+	if err := m.syncKinds(ctx, objects); err != nil {
+		return err
+	}
+	if interval := m.config.DiscoveryInterval; interval > 0 {
+		go m.watchDiscovery(ctx, interval)
+	}
+	<-ctx.Done()
+	m.stopKinds()
+	return nil
+}
+
+// watchDiscovery refreshes the watched kinds every interval, so kinds of
+// CRDs installed after the start are watched and those of removed CRDs
+// stopped.
+func (m *manager) watchDiscovery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.refreshKinds(ctx); err != nil {
+				log.FromContext(ctx).Error(err, "failed to refresh kinds", "groupVersion", m.config.GroupVersion)
+			}
+		}
+	}
+}
+
+// refreshKinds watches the kinds currently selected in the discovery of the
+// group version. The cached discovery is only dropped if a kind given by name
+// is missing from it, e.g. of a CRD installed since, other new kinds are
+// found once the cache expires.
+func (m *manager) refreshKinds(ctx context.Context) error {
+	rs, err := m.schemeClient.GetResources(ctx, m.config.GroupVersion)
+	if err != nil {
+		return err
+	}
+	if m.missingKinds(rs) {
+		m.schemeClient.Invalidate()
+		if rs, err = m.schemeClient.GetResources(ctx, m.config.GroupVersion); err != nil {
+			return err
+		}
+	}
+	objects, err := m.selectKinds(rs)
+	if err != nil {
+		return err
+	}
+	return m.syncKinds(ctx, objects)
+}
+
+// missingKinds reports whether a kind of the config or added at runtime
+// can't be resolved in the resources.
+func (m *manager) missingKinds(rs Resources) bool {
+	names := splitList(m.config.Objects)
+	m.Lock()
+	for name := range m.kinds.added {
+		names = append(names, name)
+	}
+	m.Unlock()
+	for _, name := range names {
+		if _, ok, _ := rs.Resolve(name); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resyncKinds watches the kinds selected in the discovery of the group
+// version.
+func (m *manager) resyncKinds(ctx context.Context) error {
+	rs, err := m.schemeClient.GetResources(ctx, m.config.GroupVersion)
+	if err != nil {
+		return err
+	}
+	objects, err := m.selectKinds(rs)
+	if err != nil {
+		return err
+	}
+	return m.syncKinds(ctx, objects)
+}
+
+// selectKinds returns the objects of the config with the kinds added and
+// removed at runtime.
+func (m *manager) selectKinds(rs Resources) (map[string]SchemeObject, error) {
+	objects := make(map[string]SchemeObject)
+	if !m.config.KubeWatches || m.config.Objects != "" {
+		var err error
+		if objects, err = m.config.selectObjects(rs); err != nil {
+			return nil, err
+		}
+	}
+	m.Lock()
+	defer m.Unlock()
+	for k := range m.kinds.added {
+		if o, ok, _ := rs.Resolve(k); ok {
+			objects[o.QualifiedName()] = o
+		}
+	}
+	for k := range m.kinds.removed {
+		if o, ok, _ := rs.Resolve(k); ok {
+			delete(objects, o.QualifiedName())
+		}
+	}
+	return objects, nil
+}
+
+// syncKinds starts the informers of new objects and stops those of objects
+// which are gone.
+func (m *manager) syncKinds(ctx context.Context, objects map[string]SchemeObject) error {
+	objects, err := m.accessibleKinds(ctx, objects)
+	if err != nil {
+		return err
+	}
+	m.kinds.Lock()
+	defer m.kinds.Unlock()
+	m.Lock()
+	m.objects = objects
+	var stop []string
+	for name := range m.kindWatches {
+		if _, ok := objects[name]; !ok {
+			stop = append(stop, name)
+		}
+	}
+	var start []SchemeObject
+	for name, o := range objects {
+		if _, ok := m.kindWatches[name]; !ok {
+			start = append(start, o)
+		}
+	}
+	m.Unlock()
+	for _, name := range stop {
+		m.stopKind(ctx, name)
+	}
+	sort.Slice(start, func(i, j int) bool { return start[i].Name < start[j].Name })
+	for _, o := range start {
+		if err := m.startKind(ctx, o); err != nil {
+			return fmt.Errorf("kind %s: %v", o.Name, err)
+		}
+	}
+	return nil
+}
+
+// KubeWatchServiceAccount is the ServiceAccount of the namespace of a
+// KubeWatch which must be allowed to list and watch its kinds.
+const KubeWatchServiceAccount = "default"
+
+// accessibleKinds drops the kinds of a KubeWatch which the ServiceAccount of
+// its namespace may not list and watch, kinds given by name are an error. A
+// KubeWatch can thus only read what the workloads of its namespace can, not
+// everything the role of kubewatch allows. Kinds already watched were checked
+// when they were started.
+func (m *manager) accessibleKinds(ctx context.Context, objects map[string]SchemeObject) (map[string]SchemeObject, error) {
+	ns := m.config.kubeWatchNamespace
+	if ns == "" {
+		return objects, nil
+	}
+	accessible := make(map[string]SchemeObject, len(objects))
+	for name, o := range objects {
+		m.Lock()
+		_, watched := m.kindWatches[name]
+		m.Unlock()
+		if !watched {
+			ok, err := m.schemeClient.CanWatch(ctx, ns, KubeWatchServiceAccount, o)
+			if err != nil {
+				return nil, fmt.Errorf("kind %s: %v", name, err)
+			}
+			if !ok && m.config.Objects != "" {
+				return nil, fmt.Errorf("kind %s can't be watched, serviceaccount %s/%s may not list and watch it", name, ns, KubeWatchServiceAccount)
+			}
+			if !ok {
+				continue
+			}
+		}
+		accessible[name] = o
+	}
+	return accessible, nil
+}
+
+// startKind watches the kind of the object, its informer is keyed by the
+// qualified name like the selected objects.
+func (m *manager) startKind(ctx context.Context, o SchemeObject) error {
+	name := o.QualifiedName()
+	c, err := cache.New(m.restConfig, cache.Options{
+		Scheme:    m.scheme,
+		Mapper:    m.mapper,
+		Namespace: m.config.kubeWatchNamespace,
+		DefaultSelector: cache.ObjectSelector{
+			Label: m.labelSelector,
+			Field: serverFieldSelector(m.fieldSelector),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	kctx, cancel := context.WithCancel(ctx)
+	informer, err := c.GetInformer(kctx, m.config.informerObject(o))
+	if err != nil {
+		cancel()
+		return err
+	}
+	if err := m.config.setTransform(informer, o); err != nil {
+		cancel()
+		return err
+	}
+	informer.AddEventHandler(m.eventHandler(kctx, m.config, o))
+	go func() {
+		if err := c.Start(kctx); err != nil {
+			log.FromContext(ctx).Error(err, "failed to watch kind", "kind", name)
+		}
+	}()
+	w := &kindWatch{cancel: cancel}
+	m.Lock()
+	m.kindWatches[name] = w
+	m.Unlock()
+	go func() {
+		if c.WaitForCacheSync(kctx) {
+			m.Lock()
+			w.synced = true
+			m.Unlock()
+		}
+	}()
+	log.FromContext(ctx).Info("watch kind", "kind", name, "cluster", m.cluster, "kubewatch", m.watch)
+	return nil
+}
+
+func (m *manager) stopKind(ctx context.Context, name string) {
+	m.Lock()
+	w := m.kindWatches[name]
+	delete(m.kindWatches, name)
+	m.Unlock()
+	if w == nil {
+		return
+	}
+	w.cancel()
+	log.FromContext(ctx).Info("stop kind", "kind", name, "cluster", m.cluster, "kubewatch", m.watch)
+}
+
+// stopKinds stops the informers of all kinds.
+func (m *manager) stopKinds() {
+	m.Lock()
+	watches := m.kindWatches
+	m.kindWatches = make(map[string]*kindWatch)
+	m.Unlock()
+	for _, w := range watches {
+		w.cancel()
+	}
+}
+
+// AddKinds watches kinds in addition to the selected ones while running, they
+// are resolved like the kinds of the config.
+func (m *manager) AddKinds(ctx context.Context, names ...string) error {
+	rs, err := m.schemeClient.GetResources(ctx, m.config.GroupVersion)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		o, ok, err := rs.Resolve(name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no kind %s/%s", m.config.GroupVersion, name)
+		}
+		if err := m.config.checkWatchable(o); err != nil {
+			return err
+		}
+	}
+	m.Lock()
+	for _, name := range names {
+		m.kinds.added[name] = struct{}{}
+		delete(m.kinds.removed, name)
+	}
+	m.Unlock()
+	return m.resyncKinds(ctx)
+}
+
+// RemoveKinds stops watching kinds while running.
+func (m *manager) RemoveKinds(ctx context.Context, names ...string) error {
+	m.Lock()
+	for _, name := range names {
+		m.kinds.removed[name] = struct{}{}
+		delete(m.kinds.added, name)
+	}
+	m.Unlock()
+	return m.resyncKinds(ctx)
+}
+
+// WatchedKinds returns the names of the watched kinds.
+func (m *manager) WatchedKinds() []string {
+	m.Lock()
+	defer m.Unlock()
+	names := make([]string, 0, len(m.kindWatches))
+	for name := range m.kindWatches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unsyncedKinds returns the names of the watched kinds whose caches are not
+// synced yet. The caller must hold the manager lock.
+func (m *manager) unsyncedKinds() []string {
+	var names []string
+	for name, w := range m.kindWatches {
+		if !w.synced {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// eventHandler passes the events of an informer of the kind of so to the
+// manager.
+func (m *manager) eventHandler(ctx context.Context, config Config, so SchemeObject) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if o, ok := obj.(client.Object); ok {
+				m.record(journal.TypeCreate, so, nil, o)
+				m.OnCreate(ctx, time.Now(), o, config)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			o, ok := oldObj.(client.Object)
+			n, nok := newObj.(client.Object)
+			if ok && nok {
+				m.record(journal.TypeUpdate, so, o, n)
+				m.OnUpdate(ctx, time.Now(), o, n, config)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if d, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = d.Obj
+			}
+			if o, ok := obj.(client.Object); ok {
+				m.record(journal.TypeDelete, so, o, nil)
+				m.OnDelete(ctx, time.Now(), o, config)
+			}
+		},
+	}
+}
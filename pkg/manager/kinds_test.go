@@ -0,0 +1,242 @@
+package manager
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+func TestSelectKinds(t *testing.T) {
+	rs := Resources{
+		testResource("apps/v1", "Deployment", "deployments", "deploy"),
+		testResource("apps/v1", "StatefulSet", "statefulsets", "sts"),
+		testResource("apps/v1", "DaemonSet", "daemonsets", "ds"),
+	}
+	tests := []struct {
+		name    string
+		config  Config
+		added   []string
+		removed []string
+		want    string
+	}{
+		{name: "config", config: Config{Objects: "deploy"}, want: "deployments.apps"},
+		{name: "all", config: Config{GroupVersion: "apps/v1"}, want: "daemonsets.apps,deployments.apps,statefulsets.apps"},
+		{name: "added", config: Config{Objects: "deploy"}, added: []string{"sts"}, want: "deployments.apps,statefulsets.apps"},
+		{name: "removed", config: Config{GroupVersion: "apps/v1"}, removed: []string{"ds", "deployment"}, want: "statefulsets.apps"},
+		{name: "kubewatches only", config: Config{KubeWatches: true}, want: ""},
+		{name: "kubewatches added", config: Config{KubeWatches: true}, added: []string{"ds"}, want: "daemonsets.apps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newManager(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range tt.added {
+				m.kinds.added[k] = struct{}{}
+			}
+			for _, k := range tt.removed {
+				m.kinds.removed[k] = struct{}{}
+			}
+			objects, err := m.selectKinds(rs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for name := range objects {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if s := strings.Join(got, ","); s != tt.want {
+				t.Errorf("selectKinds() = %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+// testKindsManager returns a manager watching the kinds of the resources
+// with informers which are never synced, nothing listens on the host of the
+// config.
+func testKindsManager(t *testing.T, config Config, rs Resources) *manager {
+	t.Helper()
+	m, err := newManager(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.restConfig = &rest.Config{Host: "http://127.0.0.1:1"}
+	m.scheme = runtime.NewScheme()
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, o := range rs {
+		mapper.Add(o.GVK(), meta.RESTScopeNamespace)
+	}
+	m.mapper = mapper
+	t.Cleanup(m.stopKinds)
+	return m
+}
+
+// syncTestKinds watches the kinds of the resources.
+func syncTestKinds(t *testing.T, rs Resources) (*manager, map[string]SchemeObject) {
+	t.Helper()
+	m := testKindsManager(t, Config{}, rs)
+	objects := make(map[string]SchemeObject)
+	for _, o := range rs {
+		objects[o.QualifiedName()] = o
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	if err := m.syncKinds(ctx, objects); err != nil {
+		t.Fatalf("syncKinds() error = %v", err)
+	}
+	return m, objects
+}
+
+// TestSyncKindsUnchanged checks a sync with the selected objects keeps the
+// informers running instead of restarting them, e.g. on every discovery.
+func TestSyncKindsUnchanged(t *testing.T) {
+	rs := Resources{
+		testResource("v1", "Pod", "pods", "po"),
+		testResource("apps/v1", "Deployment", "deployments", "deploy"),
+	}
+	m, objects := syncTestKinds(t, rs)
+	if got := strings.Join(m.WatchedKinds(), ","); got != "deployments.apps,pods" {
+		t.Fatalf("WatchedKinds() = %s, want deployments.apps,pods", got)
+	}
+	started := make(map[string]*kindWatch)
+	for name, w := range m.kindWatches {
+		started[name] = w
+	}
+	if err := m.syncKinds(context.Background(), objects); err != nil {
+		t.Fatalf("syncKinds() error = %v", err)
+	}
+	if len(m.kindWatches) != len(started) {
+		t.Errorf("watched kinds = %v, want %d", m.WatchedKinds(), len(started))
+	}
+	for name, w := range m.kindWatches {
+		if started[name] != w {
+			t.Errorf("kind %s was restarted", name)
+		}
+	}
+}
+
+func TestReadyCheck(t *testing.T) {
+	m, _ := syncTestKinds(t, Resources{
+		testResource("v1", "Pod", "pods", "po"),
+		testResource("apps/v1", "Deployment", "deployments", "deploy"),
+	})
+	if err := m.readyCheck(nil); err == nil {
+		t.Errorf("readyCheck() before the cache of the manager is synced succeeded")
+	}
+	m.Lock()
+	m.synced = true
+	m.Unlock()
+	if err := m.readyCheck(nil); err == nil || !strings.Contains(err.Error(), "deployments.apps,pods") {
+		t.Errorf("readyCheck() with unsynced kinds = %v", err)
+	}
+	m.Lock()
+	for _, w := range m.kindWatches {
+		w.synced = true
+	}
+	m.Unlock()
+	if err := m.readyCheck(nil); err != nil {
+		t.Errorf("readyCheck() error = %v", err)
+	}
+}
+
+// cachedSchemeClient serves the cached resources until it is invalidated.
+type cachedSchemeClient struct {
+	SchemeClient
+	cached, current Resources
+	invalidated     int
+}
+
+func (c *cachedSchemeClient) GetResources(ctx context.Context, groupVersion string) (Resources, error) {
+	return c.cached, nil
+}
+
+func (c *cachedSchemeClient) Invalidate() {
+	c.invalidated++
+	c.cached = c.current
+}
+
+func TestRefreshKinds(t *testing.T) {
+	deploy := testResource("apps/v1", "Deployment", "deployments", "deploy")
+	sts := testResource("apps/v1", "StatefulSet", "statefulsets", "sts")
+	tests := []struct {
+		name            string
+		objects         string
+		cached          Resources
+		wantInvalidated int
+		want            string
+	}{
+		{name: "cached", objects: "deploy,sts", cached: Resources{deploy, sts}, want: "deployments.apps,statefulsets.apps"},
+		{name: "missing kind", objects: "deploy,sts", cached: Resources{deploy}, wantInvalidated: 1, want: "deployments.apps,statefulsets.apps"},
+		{name: "group version", cached: Resources{deploy}, want: "deployments.apps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testKindsManager(t, Config{Objects: tt.objects, GroupVersion: "apps/v1"}, Resources{deploy, sts})
+			cli := &cachedSchemeClient{cached: tt.cached, current: Resources{deploy, sts}}
+			m.schemeClient = cli
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := m.refreshKinds(ctx); err != nil {
+				t.Fatalf("refreshKinds() error = %v", err)
+			}
+			if cli.invalidated != tt.wantInvalidated {
+				t.Errorf("invalidated %d times, want %d", cli.invalidated, tt.wantInvalidated)
+			}
+			if got := strings.Join(m.WatchedKinds(), ","); got != tt.want {
+				t.Errorf("WatchedKinds() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// accessSchemeClient allows the ServiceAccount to watch the given resources.
+type accessSchemeClient struct {
+	SchemeClient
+	allowed map[string]bool
+}
+
+func (c accessSchemeClient) CanWatch(ctx context.Context, namespace, serviceAccount string, o SchemeObject) (bool, error) {
+	return namespace == "team-a" && serviceAccount == KubeWatchServiceAccount && c.allowed[o.Resource], nil
+}
+
+func TestAccessibleKinds(t *testing.T) {
+	pods := testResource("v1", "Pod", "pods", "po")
+	secrets := testResource("v1", "Secret", "secrets")
+	objects := map[string]SchemeObject{"pods": pods, "secrets": secrets}
+	cli := accessSchemeClient{allowed: map[string]bool{"pods": true}}
+	tests := []struct {
+		name    string
+		config  Config
+		want    string
+		wantErr bool
+	}{
+		{name: "not a kubewatch", config: Config{}, want: "pods,secrets"},
+		{name: "group version", config: Config{kubeWatchNamespace: "team-a"}, want: "pods"},
+		{name: "kinds", config: Config{kubeWatchNamespace: "team-a", Objects: "po,secrets"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &manager{config: tt.config, schemeClient: cli, kindWatches: make(map[string]*kindWatch)}
+			got, err := m.accessibleKinds(context.Background(), objects)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("accessibleKinds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var names []string
+			for name := range got {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if !tt.wantErr && strings.Join(names, ",") != tt.want {
+				t.Errorf("accessibleKinds() = %v, want %s", names, tt.want)
+			}
+		})
+	}
+}
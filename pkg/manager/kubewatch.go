@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/nfyxhan/kubewatch/pkg/apis/v1alpha1"
+	"github.com/nfyxhan/kubewatch/pkg/webhook"
+)
+
+// kubeWatch is the running watch of a KubeWatch resource, stopped when the
+// resource changes or is deleted.
+type kubeWatch struct {
+	generation int64
+	manager    *manager
+	cancel     context.CancelFunc
+}
+
+func (w *kubeWatch) stop() {
+	w.cancel()
+	w.manager.Close()
+}
+
+// kubeWatchConfig returns the config of the manager with the selection of
+// the KubeWatch spec. A KubeWatch only watches the namespace it's in.
+func (c Config) kubeWatchConfig(namespace string, spec v1alpha1.KubeWatchSpec) (Config, error) {
+	for _, ns := range spec.Namespaces {
+		if ns != namespace {
+			return c, fmt.Errorf("namespace %s can't be watched, a KubeWatch only watches its namespace %s", ns, namespace)
+		}
+	}
+	c.GroupVersion = spec.GroupVersion
+	c.Objects = strings.Join(spec.Kinds, Split)
+	c.ExcludeObjects = strings.Join(spec.ExcludeKinds, Split)
+	c.Namespace = namespace
+	c.AllNamespaces = false
+	c.kubeWatchNamespace = namespace
+	c.ExcludeNamespaces = strings.Join(spec.ExcludeNamespaces, Split)
+	c.Names = spec.Names
+	c.ExcludeNames = strings.Join(spec.ExcludeNames, Split)
+	c.LabelSelector = spec.LabelSelector
+	c.FieldSelector = spec.FieldSelector
+	c.PathPrefix = spec.PathPrefix
+	c.PathTemplate = spec.PathTemplate
+	if spec.MatchMode != "" {
+		c.MatchMode = spec.MatchMode
+	}
+	if spec.IgnoreMetadata != nil {
+		c.IgnoreMetadata = *spec.IgnoreMetadata
+	}
+	if spec.EnableAnnotations != nil {
+		c.EnableAnnotations = *spec.EnableAnnotations
+	}
+	if spec.DiffFormat != "" {
+		c.DiffFormat = spec.DiffFormat
+	}
+	if spec.Scope != "" {
+		c.Scope = spec.Scope
+	}
+	if spec.MetadataOnly != nil {
+		c.MetadataOnly = *spec.MetadataOnly
+	}
+	c.StripFields = strings.Join(spec.StripFields, Split)
+	c.Filter = spec.Filter
+	c.Compute = spec.Compute
+	c.Webhooks = nil
+	for _, w := range spec.Webhooks {
+		c.Webhooks = append(c.Webhooks, webhook.Config{
+			Name:          w.Name,
+			URL:           w.URL,
+			Method:        w.Method,
+			Headers:       w.Headers,
+			Paths:         w.Paths,
+			Template:      w.Template,
+			BatchSize:     w.BatchSize,
+			BatchInterval: duration(w.BatchInterval),
+			MaxRetries:    w.MaxRetries,
+			Backoff:       duration(w.Backoff),
+			Timeout:       duration(w.Timeout),
+			QueueSize:     w.QueueSize,
+		})
+	}
+	// KubeWatches are neither recorded nor reconciled recursively
+	c.Record = ""
+	c.KubeWatches = false
+	return c, c.validate()
+}
+
+// validate checks the values of the config which are only used once events
+// arrive, as the spec of a KubeWatch is not checked by flags.
+func (c Config) validate() error {
+	if c.GroupVersion == "" && c.Objects == "" {
+		return fmt.Errorf("kinds or groupVersion are required")
+	}
+	if !slices.Contains(DiffFormats, c.DiffFormat) {
+		return fmt.Errorf("unknown diff format %q, one of %s", c.DiffFormat, strings.Join(DiffFormats, "|"))
+	}
+	if _, err := regexp.Compile(c.PathTemplate); err != nil {
+		return fmt.Errorf("invalid path template: %v", err)
+	}
+	if err := c.checkScope(); err != nil {
+		return err
+	}
+	if _, err := c.compileScripts(); err != nil {
+		return err
+	}
+	return c.checkMetadataOnly()
+}
+
+func duration(d *metav1.Duration) time.Duration {
+	if d == nil {
+		return 0
+	}
+	return d.Duration
+}
+
+// Reconcile starts, restarts and stops the watches of KubeWatch resources
+// when they are created, changed and deleted.
+func (m *manager) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	if !m.config.KubeWatches {
+		return reconcile.Result{}, nil
+	}
+	kw := &v1alpha1.KubeWatch{}
+	if err := m.Get(ctx, req.NamespacedName, kw); err != nil {
+		if apierrors.IsNotFound(err) {
+			m.stopKubeWatch(ctx, req.NamespacedName)
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	if !kw.DeletionTimestamp.IsZero() {
+		m.stopKubeWatch(ctx, req.NamespacedName)
+		return reconcile.Result{}, nil
+	}
+	m.Lock()
+	w := m.kubeWatches[req.NamespacedName]
+	m.Unlock()
+	// the status is compared too, so a failed status update is retried
+	// without restarting the watch
+	if w != nil && w.generation == kw.Generation && kw.Status.ObservedGeneration == kw.Generation {
+		return reconcile.Result{}, nil
+	}
+	var kinds []string
+	var err error
+	if w != nil && w.generation == kw.Generation {
+		kinds = w.manager.WatchedKinds()
+	} else {
+		m.stopKubeWatch(ctx, req.NamespacedName)
+		kinds, err = m.startKubeWatch(ctx, kw)
+	}
+	condition := metav1.Condition{
+		Type:               v1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             v1alpha1.ReasonStarted,
+		Message:            "watching " + strings.Join(kinds, Split),
+		ObservedGeneration: kw.Generation,
+	}
+	if err != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.ReasonFailed
+		condition.Message = err.Error()
+	}
+	kw.Status.ObservedGeneration = kw.Generation
+	kw.Status.Kinds = kinds
+	meta.SetStatusCondition(&kw.Status.Conditions, condition)
+	if err := m.Status().Update(ctx, kw); err != nil {
+		return reconcile.Result{}, err
+	}
+	// a failed start is retried with backoff
+	return reconcile.Result{Requeue: err != nil}, err
+}
+
+// startKubeWatch starts the watch of a KubeWatch and returns its kinds. The
+// watch runs until it is stopped or ctx, the context of the controller, is
+// done.
+func (m *manager) startKubeWatch(ctx context.Context, kw *v1alpha1.KubeWatch) ([]string, error) {
+	key := types.NamespacedName{Namespace: kw.Namespace, Name: kw.Name}
+	config, err := m.config.kubeWatchConfig(kw.Namespace, kw.Spec)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := m.schemeClient.GetResources(ctx, config.GroupVersion)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := config.selectObjects(rs)
+	if err != nil {
+		return nil, err
+	}
+	wm, err := newManager(config)
+	if err != nil {
+		return nil, err
+	}
+	wm.cluster = m.cluster
+	wm.watch = key.String()
+	wm.schemeClient = m.schemeClient
+	wm.restConfig = m.restConfig
+	wm.scheme = m.scheme
+	wm.mapper = m.mapper
+	if wm.outputs, err = m.outputs.withSinks(ctx, config.Webhooks); err != nil {
+		return nil, err
+	}
+	wctx, cancel := context.WithCancel(ctx)
+	w := &kubeWatch{
+		generation: kw.Generation,
+		manager:    wm,
+		cancel:     cancel,
+	}
+	wm.started = time.Now()
+	if err := wm.syncKinds(wctx, objects); err != nil {
+		w.stop()
+		return nil, err
+	}
+	if config.DiscoveryInterval > 0 {
+		go wm.watchDiscovery(wctx, config.DiscoveryInterval)
+	}
+	kinds := wm.WatchedKinds()
+	m.Lock()
+	m.kubeWatches[key] = w
+	m.Unlock()
+	log.FromContext(ctx).Info("started watch", "kubewatch", key, "kinds", kinds)
+	return kinds, nil
+}
+
+func (m *manager) stopKubeWatch(ctx context.Context, key types.NamespacedName) {
+	m.Lock()
+	w := m.kubeWatches[key]
+	delete(m.kubeWatches, key)
+	m.Unlock()
+	if w == nil {
+		return
+	}
+	w.stop()
+	log.FromContext(ctx).Info("stopped watch", "kubewatch", key)
+}
+
+// stopKubeWatches stops the watches of all KubeWatch resources.
+func (m *manager) stopKubeWatches() {
+	m.Lock()
+	watches := m.kubeWatches
+	m.kubeWatches = make(map[types.NamespacedName]*kubeWatch)
+	m.Unlock()
+	for _, w := range watches {
+		w.stop()
+	}
+}
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/nfyxhan/kubewatch/pkg/apis/v1alpha1"
+)
+
+func TestKubeWatchConfig(t *testing.T) {
+	base := Config{
+		DiffFormat:     DiffFields,
+		MatchMode:      MatchPrefix,
+		IgnoreMetadata: true,
+		Record:         "events.ndjson",
+		KubeWatches:    true,
+		Namespace:      "default",
+	}
+	ignore := false
+	config, err := base.kubeWatchConfig("team-a", v1alpha1.KubeWatchSpec{
+		GroupVersion:   "apps/v1",
+		Kinds:          []string{"deployment", "sts"},
+		Namespaces:     []string{"team-a"},
+		IgnoreMetadata: &ignore,
+		Webhooks: []v1alpha1.Webhook{{
+			URL:     "http://example.com",
+			Timeout: &metav1.Duration{Duration: time.Second},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("kubeWatchConfig() error = %v", err)
+	}
+	if config.Objects != "deployment,sts" || config.Namespace != "team-a" || config.AllNamespaces {
+		t.Errorf("selection = %q %q %v", config.Objects, config.Namespace, config.AllNamespaces)
+	}
+	if config.IgnoreMetadata || config.MatchMode != MatchPrefix || config.DiffFormat != DiffFields {
+		t.Errorf("defaults = %v %q %q", config.IgnoreMetadata, config.MatchMode, config.DiffFormat)
+	}
+	if config.Record != "" || config.KubeWatches {
+		t.Errorf("record %q and kubewatches %v are not cleared", config.Record, config.KubeWatches)
+	}
+	if len(config.Webhooks) != 1 || config.Webhooks[0].Timeout != time.Second {
+		t.Errorf("webhooks = %+v", config.Webhooks)
+	}
+
+	invalid := []v1alpha1.KubeWatchSpec{
+		{},
+		{GroupVersion: "v1", DiffFormat: "html"},
+		{GroupVersion: "v1", PathTemplate: "("},
+		{GroupVersion: "v1", Scope: ScopeStatus, MetadataOnly: &[]bool{true}[0]},
+		{GroupVersion: "v1", Filter: "new.spec.replicas >"},
+		{GroupVersion: "v1", Compute: []string{"replicas"}},
+	}
+	for _, spec := range invalid {
+		if _, err := base.kubeWatchConfig("team-a", spec); err == nil {
+			t.Errorf("kubeWatchConfig(%+v), want error", spec)
+		}
+	}
+}
+
+// TestKubeWatchNamespace checks a KubeWatch only watches the objects of its
+// namespace.
+func TestKubeWatchNamespace(t *testing.T) {
+	base := Config{DiffFormat: DiffFields, MatchMode: MatchPrefix, AllNamespaces: true}
+	if _, err := base.kubeWatchConfig("team-a", v1alpha1.KubeWatchSpec{GroupVersion: "v1", Namespaces: []string{"team-a", "team-b"}}); err == nil {
+		t.Errorf("kubeWatchConfig() with another namespace succeeded")
+	}
+	config, err := base.kubeWatchConfig("team-a", v1alpha1.KubeWatchSpec{GroupVersion: "v1"})
+	if err != nil {
+		t.Fatalf("kubeWatchConfig() error = %v", err)
+	}
+	if config.Namespace != "team-a" || config.AllNamespaces {
+		t.Errorf("namespace = %q %v, want team-a", config.Namespace, config.AllNamespaces)
+	}
+	ms, err := config.getMatchers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ns, want := range map[string]bool{"team-a": true, "team-a-dev": false, "team-b": false, "": false} {
+		if got := ms.namespaces.Match(ns); got != want {
+			t.Errorf("namespace %q matched = %v, want %v", ns, got, want)
+		}
+	}
+
+	pods := testResource("v1", "Pod", "pods", "po")
+	pods.Namespaced = true
+	rs := Resources{pods, testResource("v1", "Node", "nodes", "no")}
+	objects, err := config.selectObjects(rs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["nodes"]; ok || len(objects) != 1 {
+		t.Errorf("selectObjects() = %v, want pods only", objects)
+	}
+	config.Objects = "no"
+	if _, err := config.selectObjects(rs); err == nil {
+		t.Errorf("selectObjects() of a cluster scoped kind succeeded")
+	}
+}
+
+// conflictClient fails the first status updates with a conflict.
+type conflictClient struct {
+	client.Client
+	conflicts int
+}
+
+func (c *conflictClient) Status() client.StatusWriter {
+	return conflictStatusWriter{StatusWriter: c.Client.Status(), c: c}
+}
+
+type conflictStatusWriter struct {
+	client.StatusWriter
+	c *conflictClient
+}
+
+func (w conflictStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if w.c.conflicts > 0 {
+		w.c.conflicts--
+		return apierrors.NewConflict(v1alpha1.GroupVersion.WithResource("kubewatches").GroupResource(), obj.GetName(), nil)
+	}
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+// TestReconcileStatusRetry checks the status of a running watch is written
+// when its first update failed, without restarting the watch.
+func TestReconcileStatusRetry(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	key := types.NamespacedName{Namespace: "team-a", Name: "web"}
+	kw := &v1alpha1.KubeWatch{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, Generation: 1}}
+	c := &conflictClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(kw).Build(), conflicts: 1}
+	w := &kubeWatch{
+		generation: 1,
+		manager:    &manager{kindWatches: map[string]*kindWatch{"deployments.apps": {}}},
+		cancel:     func() {},
+	}
+	m := &manager{
+		Client:      c,
+		config:      Config{KubeWatches: true},
+		kubeWatches: map[types.NamespacedName]*kubeWatch{key: w},
+	}
+	req := reconcile.Request{NamespacedName: key}
+	if _, err := m.Reconcile(context.Background(), req); !apierrors.IsConflict(err) {
+		t.Fatalf("Reconcile() error = %v, want a conflict", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := m.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+	if m.kubeWatches[key] != w {
+		t.Errorf("the watch was restarted")
+	}
+	got := &v1alpha1.KubeWatch{}
+	if err := c.Get(context.Background(), key, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.ObservedGeneration != 1 || len(got.Status.Kinds) != 1 || got.Status.Kinds[0] != "deployments.apps" {
+		t.Errorf("status = %+v, want generation 1 and kinds deployments.apps", got.Status)
+	}
+}
@@ -4,35 +4,39 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"math/rand"
+	"net/http"
 	"os"
 	"reflect"
-	"regexp"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
-	"github.com/jedib0t/go-pretty/table"
-	"github.com/r3labs/diff/v3"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/util/workqueue"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/utils/strings/slices"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
-	"sigs.k8s.io/controller-runtime/pkg/event"
-	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/controller-runtime/pkg/reconcile"
-	"sigs.k8s.io/controller-runtime/pkg/source"
+	ctrlmanager "sigs.k8s.io/controller-runtime/pkg/manager"
 
+	"github.com/nfyxhan/kubewatch/pkg/apis/v1alpha1"
+	"github.com/nfyxhan/kubewatch/pkg/journal"
 	"github.com/nfyxhan/kubewatch/pkg/metrics"
+	"github.com/nfyxhan/kubewatch/pkg/output"
+	"github.com/nfyxhan/kubewatch/pkg/plugins"
 	"github.com/nfyxhan/kubewatch/pkg/utils"
+	"github.com/nfyxhan/kubewatch/pkg/webhook"
 )
 
 const (
@@ -43,6 +47,7 @@ const (
 var (
 	AnnotationsPaths = []string{
 		"metadata" + Split + "annotations",
+		"ObjectMeta" + Split + "Annotations",
 	}
 )
 
@@ -52,37 +57,89 @@ var ignorePath = map[string]struct{}{
 	"metadata" + Split + "resourceVersion": {},
 	"metadata" + Split + "generation":      {},
 	"metadata" + Split + "managedFields":   {},
+	// typed objects of plugins
+	"TypeMeta":                               {},
+	"ObjectMeta" + Split + "ResourceVersion": {},
+	"ObjectMeta" + Split + "Generation":      {},
+	"ObjectMeta" + Split + "ManagedFields":   {},
 }
 
 func init() {
 	for k, v := range ignorePath {
 		ignorePath["Object"+Split+k] = v
 	}
+	// paths of unstructured objects start with Object
+	AnnotationsPaths = append(AnnotationsPaths, "Object"+Split+AnnotationsPaths[0])
 }
 
 type Config struct {
-	ExcludeObjects     string            `json:"excludeObjects,omitempty"`
-	Objects            string            `json:"objects,omitempty"`
-	Namespace          string            `json:"namespace,omitempty"`
-	GroupVersion       string            `json:"groupVersion,omitempty"`
-	Names              []string          `json:"names,omitempty"`
-	Labels             map[string]string `json:"labels,omitempty"`
-	EnableAnnotations  bool              `json:"enableAnnotations"`
-	SliceOrdering      bool              `json:"sliceOrdering"`
-	ColumnWidthMax     int               `json:"columnWidthMax"`
-	RowWidthMax        int               `json:"rowWidthMax"`
-	IgnoreMetadata     bool              `json:"ignoreMetadata"`
-	PathPrefix         string            `json:"pathPrefix,omitempty"`
-	PathTemplate       string            `json:"pathTemplate"`
-	ToComplete         string            `json:"toComplete,omitempty"`
-	MaxRows            int               `json:"maxRows"`
+	ExcludeObjects    string            `json:"excludeObjects,omitempty"`
+	Objects           string            `json:"objects,omitempty"`
+	Namespace         string            `json:"namespace,omitempty"`
+	AllNamespaces     bool              `json:"allNamespaces,omitempty"`
+	ExcludeNamespaces string            `json:"excludeNamespaces,omitempty"`
+	MatchMode         string            `json:"matchMode,omitempty"`
+	GroupVersion      string            `json:"groupVersion,omitempty"`
+	Names             []string          `json:"names,omitempty"`
+	ExcludeNames      string            `json:"excludeNames,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	LabelSelector     string            `json:"labelSelector,omitempty"`
+	FieldSelector     string            `json:"fieldSelector,omitempty"`
+	EnableAnnotations bool              `json:"enableAnnotations"`
+	SliceOrdering     bool              `json:"sliceOrdering"`
+	ColumnWidthMax    int               `json:"columnWidthMax"`
+	RowWidthMax       int               `json:"rowWidthMax"`
+	IgnoreMetadata    bool              `json:"ignoreMetadata"`
+	PathPrefix        string            `json:"pathPrefix,omitempty"`
+	PathTemplate      string            `json:"pathTemplate"`
+	ToComplete        string            `json:"toComplete,omitempty"`
+	MaxRows           int               `json:"maxRows"`
+	Output            string            `json:"output,omitempty"`
+	// Scope limits the changes to the status or the spec of the objects.
+	Scope      string `json:"scope,omitempty"`
+	DiffFormat string `json:"diffFormat,omitempty"`
+	// MetadataOnly watches only the metadata of the objects, StripFields are
+	// removed from the objects before they are cached.
+	MetadataOnly bool   `json:"metadataOnly,omitempty"`
+	StripFields  string `json:"stripFields,omitempty"`
+	// Filter is a Starlark expression of old and new, updates are dropped
+	// if it's false. Compute are name=expression pairs of obj, shown with
+	// the changes and exported as metrics.
+	Filter             string           `json:"filter,omitempty"`
+	Compute            []string         `json:"compute,omitempty"`
+	SummaryFields      string           `json:"summaryFields,omitempty"`
+	MetricRules        string           `json:"metricRules,omitempty"`
+	Record             string           `json:"record,omitempty"`
+	Webhooks           []webhook.Config `json:"webhooks,omitempty"`
+	Context            string           `json:"context,omitempty"`
+	AllContexts        bool             `json:"allContexts,omitempty"`
 	MetricsBindAddress string
+	// HealthProbeBindAddress and the leader election are set by serve.
+	HealthProbeBindAddress  string `json:"healthProbeBindAddress,omitempty"`
+	LeaderElection          bool   `json:"leaderElection,omitempty"`
+	LeaderElectionID        string `json:"leaderElectionID,omitempty"`
+	LeaderElectionNamespace string `json:"leaderElectionNamespace,omitempty"`
+	// DiscoveryInterval is how often the watched kinds are refreshed, never
+	// if zero.
+	DiscoveryInterval time.Duration `json:"discoveryInterval,omitempty"`
+	// KubeWatches reconciles KubeWatch resources into watches, only the
+	// kinds given by Objects are watched in addition.
+	KubeWatches bool `json:"kubeWatches,omitempty"`
+	// kubeWatchNamespace is the namespace of the KubeWatch of the config,
+	// only its objects are watched.
+	kubeWatchNamespace string
 }
 
+// GetKubeConfig returns the rest config of the first context of the config,
+// or of the current context.
 func (c Config) GetKubeConfig() (*rest.Config, error) {
 	flag.CommandLine.Set("kubeconfig", utils.Kubeconfig)
 	flag.Parse()
-	cfg, err := config.GetConfig()
+	var kubeContext string
+	if contexts := splitList(c.Context); len(contexts) > 0 {
+		kubeContext = contexts[0]
+	}
+	cfg, err := config.GetConfigWithContext(kubeContext)
 	if err != nil {
 		return nil, err
 	}
@@ -91,12 +148,73 @@ func (c Config) GetKubeConfig() (*rest.Config, error) {
 }
 
 type manager struct {
+	sync.Mutex
 	mgr          ctrl.Manager
 	schemeClient SchemeClient
+	restConfig   *rest.Config
+	scheme       *runtime.Scheme
+	mapper       meta.RESTMapper
 	client.Client
+	*outputs
 	objects map[string]SchemeObject
-	table   func() table.Writer
-	rows    []table.Row
+	// cluster is the kubeconfig context of the manager, empty for the
+	// current context.
+	cluster string
+	// watch is the namespace/name of the KubeWatch of the manager.
+	watch         string
+	config        Config
+	labelSelector labels.Selector
+	fieldSelector fields.Selector
+	matchers      matchers
+	lastChanges   map[string]time.Time
+	rules         *metrics.RuleSet
+	scripts       *scripts
+	// started is when the watch started, objects created before are not
+	// reported as created.
+	started time.Time
+	synced  bool
+	// kubeWatches are the running watches of KubeWatch resources.
+	kubeWatches map[types.NamespacedName]*kubeWatch
+	kindWatches map[string]*kindWatch
+	kinds       kinds
+}
+
+type matchers struct {
+	namespaces        *matcher
+	excludeNamespaces *matcher
+	names             *matcher
+	excludeNames      *matcher
+}
+
+func (c Config) getMatchers() (matchers, error) {
+	var ms matchers
+	var err error
+	namespaces := splitList(c.Namespace)
+	if c.AllNamespaces {
+		namespaces = nil
+	}
+	// namespaces match exactly unless a match mode is given, names by prefix
+	mode := c.MatchMode
+	if mode == "" {
+		mode = MatchExact
+	}
+	includeMode := mode
+	if c.kubeWatchNamespace != "" {
+		includeMode = MatchExact
+	}
+	if ms.namespaces, err = newMatcher(includeMode, namespaces); err != nil {
+		return ms, err
+	}
+	if ms.excludeNamespaces, err = newMatcher(mode, splitList(c.ExcludeNamespaces)); err != nil {
+		return ms, err
+	}
+	if ms.names, err = newMatcher(c.MatchMode, c.Names); err != nil {
+		return ms, err
+	}
+	if ms.excludeNames, err = newMatcher(c.MatchMode, splitList(c.ExcludeNames)); err != nil {
+		return ms, err
+	}
+	return ms, nil
 }
 
 func NewManager(ctx context.Context, config Config, cli SchemeClient) (ObjectClient, error) {
@@ -111,101 +229,270 @@ func NewManager(ctx context.Context, config Config, cli SchemeClient) (ObjectCli
 	scheme := runtime.NewScheme()
 	_ = corev1.AddToScheme(scheme)
 	sc := NewSchemeClient(cfg)
-	objectMap, err := sc.GetObjectMap(ctx, config.GroupVersion)
-	if err != nil {
-		return nil, err
-	}
-	objectsStr := config.Objects
-	objects := make(map[string]SchemeObject, 0)
-	for _, obj := range strings.Split(objectsStr, ",") {
-		if o, ok := objectMap[obj]; ok {
-			objects[o.Name] = o
+	objects := make(map[string]SchemeObject)
+	if !config.KubeWatches || config.Objects != "" {
+		rs, err := sc.GetResources(ctx, config.GroupVersion)
+		if err != nil {
+			return nil, err
 		}
-	}
-	if len(objects) == 0 {
-		for _, o := range objectMap {
-			objects[o.Name] = o
+		if objects, err = config.selectObjects(rs); err != nil {
+			return nil, err
 		}
-	}
-	excludeObjectsStr := config.ExcludeObjects
-	for _, obj := range strings.Split(excludeObjectsStr, ",") {
-		if o, ok := objectMap[obj]; ok {
-			delete(objects, o.Name)
+		if unknown := rs.Unknown(splitList(config.Objects)); len(unknown) > 0 {
+			fmt.Fprintln(os.Stderr, "kinds not found, waiting for them to be installed: ", unknown)
 		}
 	}
 	kinds := make([]string, 0)
 	for k := range objects {
 		kinds = append(kinds, k)
 	}
-	fmt.Println("watching ", kinds)
+	if config.Context != "" {
+		fmt.Fprintln(os.Stderr, "watching ", config.Context, kinds)
+	} else {
+		fmt.Fprintln(os.Stderr, "watching ", kinds)
+	}
 	if err := cli.AddToScheme(ctx, scheme); err != nil {
 		return nil, err
 	}
+	if err := plugins.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	r, err := newManager(config)
+	if err != nil {
+		return nil, err
+	}
+	selectors := cache.SelectorsByObject{
+		&corev1.Namespace{}: {},
+	}
+	var owner client.Object = &corev1.Namespace{}
+	if config.KubeWatches {
+		if err := v1alpha1.AddToScheme(scheme); err != nil {
+			return nil, err
+		}
+		owner = &v1alpha1.KubeWatch{}
+		selectors[owner] = cache.ObjectSelector{}
+	}
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
-		Scheme:             scheme,
-		MetricsBindAddress: config.MetricsBindAddress,
+		Scheme:                     scheme,
+		MetricsBindAddress:         config.MetricsBindAddress,
+		HealthProbeBindAddress:     config.HealthProbeBindAddress,
+		LeaderElection:             config.LeaderElection,
+		LeaderElectionID:           config.LeaderElectionID,
+		LeaderElectionNamespace:    config.LeaderElectionNamespace,
+		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
+		NewCache: cache.BuilderWithOptions(cache.Options{
+			SelectorsByObject: selectors,
+			DefaultSelector: cache.ObjectSelector{
+				Label: r.labelSelector,
+				Field: serverFieldSelector(r.fieldSelector),
+			},
+		}),
 	})
 	if err != nil {
 		return nil, err
 	}
-	r := &manager{
-		mgr:          mgr,
-		schemeClient: sc,
-		Client:       mgr.GetClient(),
-		objects:      objects,
-		table: func() table.Writer {
-			return NewTable(config)
-		},
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return nil, err
 	}
-	builder := ctrl.NewControllerManagedBy(mgr).For(&corev1.Namespace{})
-	writer := os.Stdout
-	for _, obj := range objects {
-		builder = builder.Watches(&source.Kind{Type: obj.Object}, &handler.Funcs{
-			CreateFunc: func(e event.CreateEvent, w workqueue.RateLimitingInterface) {
-				obj := e.Object
-				if !r.filterObject(ctx, obj, config, "create") {
-					return
-				}
-				r.log(obj).Info("object created")
-			},
-			UpdateFunc: func(e event.UpdateEvent, w workqueue.RateLimitingInterface) {
-				objNew := e.ObjectNew
-				if !r.filterObject(ctx, objNew, config, "update") {
-					return
-				}
-				objOld := e.ObjectOld
-				r.log(objNew).Info("object updated")
-				r.DiffObject(objNew, objOld, config, writer)
-			},
-			DeleteFunc: func(e event.DeleteEvent, w workqueue.RateLimitingInterface) {
-				obj := e.Object
-				if !r.filterObject(ctx, obj, config, "delete") {
-					return
-				}
-				r.log(obj).Info("object deleted")
-				metr := metrics.GetMetricsFieldValues()
-				gvk := obj.GetObjectKind().GroupVersionKind()
-				m, err := metr.CurryWith(map[string]string{
-					"group":     gvk.Group,
-					"version":   gvk.Version,
-					"kind":      gvk.Kind,
-					"namespace": obj.GetNamespace(),
-					"name":      obj.GetName(),
-				})
-				if err != nil {
-					r.log(obj).Info("deleted object metrics", "err", err)
-				} else {
-					m.Reset()
-				}
-			},
-		})
+	if err := mgr.AddReadyzCheck("cache", r.readyCheck); err != nil {
+		return nil, err
+	}
+	r.mgr = mgr
+	r.schemeClient = sc
+	r.restConfig = mgr.GetConfig()
+	r.scheme = mgr.GetScheme()
+	r.mapper = mgr.GetRESTMapper()
+	r.Client = mgr.GetClient()
+	r.objects = objects
+	if err := ctrl.NewControllerManagedBy(mgr).For(owner).Complete(r); err != nil {
+		return nil, err
 	}
-	if err := builder.Complete(r); err != nil {
+	// the kinds are watched by their own caches, so they can be added and
+	// removed while running
+	if err := mgr.Add(ctrlmanager.RunnableFunc(r.runKinds)); err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
+// newManager builds the parts of the manager which don't need a cluster
+// connection, shared by live watches and replays.
+func newManager(config Config) (*manager, error) {
+	ls, fs, err := config.GetSelectors()
+	if err != nil {
+		return nil, err
+	}
+	ms, err := config.getMatchers()
+	if err != nil {
+		return nil, err
+	}
+	if err := config.checkScope(); err != nil {
+		return nil, err
+	}
+	if err := config.checkMetadataOnly(); err != nil {
+		return nil, err
+	}
+	scripts, err := config.compileScripts()
+	if err != nil {
+		return nil, err
+	}
+	var rules *metrics.RuleSet
+	if config.MetricRules != "" {
+		if rules, err = metrics.LoadRules(config.MetricRules); err != nil {
+			return nil, err
+		}
+	}
+	return &manager{
+		cluster:     config.Context,
+		config:      config,
+		lastChanges: make(map[string]time.Time),
+		rules:       rules,
+		scripts:     scripts,
+		kubeWatches: make(map[types.NamespacedName]*kubeWatch),
+		kindWatches: make(map[string]*kindWatch),
+		kinds: kinds{
+			added:   make(map[string]struct{}),
+			removed: make(map[string]struct{}),
+		},
+		labelSelector: ls,
+		fieldSelector: fs,
+		matchers:      ms,
+	}, nil
+}
+
+// outputs are the destinations of events, shared by the managers of all
+// watched clusters.
+type outputs struct {
+	writer  output.Writer
+	display output.Writer
+	// ownDisplay is set for the outputs which opened the display, the
+	// outputs of KubeWatches only share it.
+	ownDisplay bool
+	sinks      []*webhook.Sink
+	recorder   *journal.Recorder
+	// closeOnce closes outputs shared by the managers of several clusters
+	// once.
+	closeOnce sync.Once
+}
+
+// newOutputs opens the output, webhooks and journal of the config. They are
+// only opened when watching starts, so completions don't touch them.
+func newOutputs(ctx context.Context, config Config) (*outputs, error) {
+	writer, err := output.NewWriter(config.Output, os.Stdout, output.Options{
+		ColumnWidthMax: config.ColumnWidthMax,
+		RowWidthMax:    config.RowWidthMax,
+		MaxRows:        config.MaxRows,
+	})
+	if err != nil {
+		return nil, err
+	}
+	o, err := (&outputs{display: writer}).withSinks(ctx, config.Webhooks)
+	if err != nil {
+		output.Close(writer)
+		return nil, err
+	}
+	o.ownDisplay = true
+	if config.Record != "" {
+		if o.recorder, err = journal.NewRecorder(config.Record); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// withSinks returns outputs writing to the display of o and to the given
+// webhooks.
+func (o *outputs) withSinks(ctx context.Context, webhooks []webhook.Config) (*outputs, error) {
+	out := &outputs{
+		display: o.display,
+	}
+	writers := append([]output.Writer{o.display}, plugins.Writers()...)
+	for _, c := range webhooks {
+		s, err := webhook.New(ctx, c)
+		if err != nil {
+			out.close()
+			return nil, err
+		}
+		out.sinks = append(out.sinks, s)
+		writers = append(writers, s)
+	}
+	out.writer = output.MultiWriter(writers...)
+	return out, nil
+}
+
+// close flushes and stops the webhook sinks and the journal, and restores
+// the terminal of an interactive output and stops the exec plugins.
+func (o *outputs) close() {
+	o.closeOnce.Do(func() {
+		if o.ownDisplay {
+			output.Close(o.display)
+			plugins.CloseExec()
+		}
+		for _, s := range o.sinks {
+			s.Close()
+		}
+		if o.recorder != nil {
+			o.recorder.Close()
+		}
+	})
+}
+
+func (m *manager) record(eventType string, o SchemeObject, objOld, objNew client.Object) {
+	if m.recorder == nil {
+		return
+	}
+	res := &journal.Resource{
+		Name:         o.Resource,
+		SingularName: o.Name,
+		ShortNames:   o.ShortNames,
+	}
+	if err := m.recorder.Record(time.Now(), m.cluster, eventType, res, objOld, objNew); err != nil {
+		obj := objNew
+		if obj == nil {
+			obj = objOld
+		}
+		m.log(obj).Error(err, "failed to record event")
+	}
+}
+
+func (m *manager) OnCreate(ctx context.Context, t time.Time, obj client.Object, config Config) {
+	if !m.filterObject(ctx, obj, config, "create") || !hooksFor(obj).Filter(nil, obj) {
+		return
+	}
+	m.log(obj).Info("object created")
+	m.observeEvent("create", obj)
+	// the informer also reports all existing objects as created on start
+	m.observeSnapshot(obj, config)
+	if !m.isInitialObject(obj) {
+		m.writeObjectEvent(t, output.OpCreated, obj, config)
+	}
+}
+
+func (m *manager) OnUpdate(ctx context.Context, t time.Time, objOld, objNew client.Object, config Config) {
+	if !m.filterObject(ctx, objNew, config, "update") || !hooksFor(objNew).Filter(objOld, objNew) || !m.filterScript(objOld, objNew) {
+		return
+	}
+	m.log(objNew).Info("object updated")
+	m.observeEvent("update", objNew)
+	if err := m.rules.Observe(m.cluster, objNew); err != nil {
+		m.log(objNew).Error(err, "failed to observe metric rules")
+	}
+	m.observePlugins(objNew)
+	// the scripts are evaluated once for the metrics and the changes
+	from, to := m.computeValues(objOld), m.computeValues(objNew)
+	m.observeComputed(objNew, to)
+	m.diffObject(t, objNew, objOld, config, m.writer, m.computedChanges(from, to))
+}
+
+func (m *manager) OnDelete(ctx context.Context, t time.Time, obj client.Object, config Config) {
+	if !m.filterObject(ctx, obj, config, "delete") || !hooksFor(obj).Filter(obj, nil) {
+		return
+	}
+	m.log(obj).Info("object deleted")
+	m.observeEvent("delete", obj)
+	m.writeObjectEvent(t, output.OpDeleted, obj, config)
+}
+
 func (m *manager) log(object client.Object) logr.Logger {
 	return log.FromContext(context.Background()).
 		WithValues("name", object.GetName()).
@@ -216,204 +503,133 @@ func (m *manager) log(object client.Object) logr.Logger {
 func (m *manager) filterObject(ctx context.Context, obj client.Object, config Config, action string) bool {
 	name := obj.GetName()
 	namespace := obj.GetNamespace()
-	if ns := config.Namespace; ns != "" && !strings.Contains(namespace, ns) {
+	ms := m.matchers
+	if !ms.namespaces.Match(namespace) || ms.excludeNamespaces.Exclude(namespace) {
 		return false
 	}
-	names := config.Names
-	if len(names) > 0 {
-		var check bool
-		for _, n := range names {
-			if len(names) > 0 && strings.Contains(name, n) {
-				check = true
-				break
-			}
-		}
-		if !check {
-			return false
-		}
+	if !ms.names.Match(name) || ms.excludeNames.Exclude(name) {
+		return false
+	}
+	if !matchSelectors(obj, m.labelSelector, m.fieldSelector) {
+		return false
 	}
 	kind := obj.GetObjectKind().GroupVersionKind().Kind
 	log.FromContext(ctx).Info(action, "kind", kind, "namespace", namespace, "name", name)
 	return true
 }
 
-func (m *manager) DiffObject(objNew, objOld client.Object, config Config, w io.Writer) {
-	changeLogs, _ := diff.Diff(objOld, objNew, diff.SliceOrdering(config.SliceOrdering))
-	metr := metrics.GetMetricsFieldValues()
-	gvk := objNew.GetObjectKind().GroupVersionKind()
-	var rows []table.Row
-	for _, changeLog := range changeLogs {
-		t := changeLog.Type
-		path := strings.Join(changeLog.Path, Split)
-		if !config.IgnoreMetadata {
-			ignorePath = make(map[string]struct{})
-		}
-		if _, ok := ignorePath[path]; ok {
-			continue
-		}
-		p := strings.ToLower(path)
-		var ignore bool
-		for k := range ignorePath {
-			if strings.HasPrefix(p, strings.ToLower(k)) {
-				ignore = true
-				break
-			}
-		}
-		if !config.EnableAnnotations {
-			for _, k := range AnnotationsPaths {
-				if strings.HasPrefix(p, strings.ToLower(k)) {
-					ignore = true
-					break
-				}
-			}
-		}
-		if ignore {
-			continue
-		}
-		if pathPrefix := config.PathPrefix; pathPrefix != "" {
-			if !strings.HasPrefix(p, strings.ToLower(pathPrefix)) {
-				continue
-			}
-			path = path[len(pathPrefix):]
-		}
-		path = strings.ReplaceAll(path, Split, SplitPrint)
-		if t := config.PathTemplate; t != "" {
-			if ok, _ := regexp.MatchString(t, path); !ok {
-				continue
-			}
-		}
-		path = strings.TrimPrefix(path, "Object/")
-		labels := []string{
-			gvk.Group,
-			gvk.Version,
-			gvk.Kind,
-			objNew.GetNamespace(),
-			objNew.GetName(),
-			path,
-		}
-		fn := func(v interface{}) {
-			if v == nil {
-				return
-			}
-			var vvv float64
-			t := reflect.ValueOf(v)
-			if t.CanAddr() {
-				v = t.Interface()
-			}
-			switch vv := v.(type) {
-			case float32, float64:
-				vvv = t.Float()
-			case int, int16, int32, int64, int8:
-				vvv = float64(t.Int())
-			case bool:
-				if vv {
-					vvv = 1
-				} else {
-					vvv = 0
-				}
-			case string:
-				f, err := strconv.Atoi(vv)
-				if err == nil {
-					vvv = float64(f)
-					break
-				}
-				switch vv {
-				case "true", "True":
-					vvv = 1
-				case "false", "False":
-					vvv = 0
-				default:
-					v = nil
-				}
-			default:
-				v = nil
-			}
-			if v == nil {
-				metr.DeleteLabelValues(labels...)
-			} else {
-				metr.WithLabelValues(labels...).Set(vvv)
-			}
-		}
-		from := changeLog.From
-		if from == nil {
-			from = "<nil>"
-		}
-		to := changeLog.To
-		fn(to)
-		if to == nil {
-			to = "<nil>"
-		}
-		rows = append(rows, table.Row{
-			"",
-			path,
-			from,
-			to,
-			t,
-		})
-	}
-	if len(rows) == 0 {
+func (m *manager) DiffObject(objNew, objOld client.Object, config Config, w output.Writer) {
+	m.diffObject(time.Now(), objNew, objOld, config, w, m.computedChanges(m.computeValues(objOld), m.computeValues(objNew)))
+}
+
+func (m *manager) diffObject(now time.Time, objNew, objOld client.Object, config Config, w output.Writer, computed []output.Change) {
+	hooks := hooksFor(objNew)
+	objNew, objOld = hooks.Normalize(objNew), hooks.Normalize(objOld)
+	changes, err := diffChanges(objNew, objOld, config)
+	if err != nil {
+		m.log(objNew).Error(err, "failed to diff object")
 		return
 	}
-	kind := objNew.GetObjectKind().GroupVersionKind().Kind
-	now := time.Now().Local().Format("15:04:05.999")
-	key := fmt.Sprintf("%s/%s", kind, objNew.GetName())
-	row := table.Row{
-		utils.ColorString(utils.Blue, now),
-		utils.ColorString(utils.Blue, key),
-		"",
-		"",
-		"",
+	m.observeChanges(now, objNew, changes, config)
+	gvk := objNew.GetObjectKind().GroupVersionKind()
+	if len(changes) == 0 {
+		return
 	}
-	rows = append([]table.Row{row}, rows...)
-	maxRows := config.MaxRows
-	if len(rows) > maxRows {
-		maxRows = len(rows)
+	e := output.Event{
+		Time:      now,
+		Cluster:   m.cluster,
+		Watch:     m.watch,
+		Group:     gvk.Group,
+		Version:   gvk.Version,
+		Kind:      gvk.Kind,
+		Namespace: objNew.GetNamespace(),
+		Name:      objNew.GetName(),
+		Changes:   append(changes, computed...),
+		Old:       objOld,
+		New:       objNew,
+	}
+	if f := config.DiffFormat; f == DiffUnified || f == DiffSideBySide {
+		if e.Diff, err = textDiff(objNew, objOld, config, computed); err != nil {
+			m.log(objNew).Error(err, "failed to render diff")
+			return
+		}
+		if e.Diff == "" {
+			return
+		}
+		e.Changes = nil
 	}
-	m.rows = append(m.rows, rows...)
-	if len(m.rows) > maxRows {
-		m.rows = m.rows[len(m.rows)-maxRows:]
+	if err := w.Write(e); err != nil {
+		m.log(objNew).Error(err, "failed to write event")
 	}
-	t := m.table()
-	t.AppendRows(m.rows)
-	s := t.Render()
-	fmt.Fprintf(w, "\033c%s", s)
 }
 
-func NewTable(config Config) table.Writer {
-	t := table.NewWriter()
-	columnConfigs := make([]table.ColumnConfig, 0)
-	header := table.Row{"time", "key", "from", "to", "op"}
-	for i := 0; i < len(header); i++ {
-		columnConfigs = append(columnConfigs, table.ColumnConfig{
-			WidthMax: config.ColumnWidthMax,
-			Number:   i + 1,
-		})
+// Start runs the controller manager and waits for its cache. The kinds are
+// watched in their own caches by runKinds, readyCheck waits for them.
+func (m *manager) Start(ctx context.Context) error {
+	if m.outputs == nil {
+		out, err := newOutputs(ctx, m.config)
+		if err != nil {
+			return err
+		}
+		m.outputs = out
+	}
+	m.started = time.Now()
+	go m.mgr.Start(ctx)
+	if ok := m.mgr.GetCache().WaitForCacheSync(ctx); ok {
+		m.Lock()
+		m.synced = true
+		m.Unlock()
+		return nil
 	}
-	t.SetColumnConfigs(columnConfigs)
-	t.AppendHeader(header)
-	// t.SetAutoIndex(true)
-	t.SetAllowedRowLength(config.RowWidthMax)
-	return t
+	return fmt.Errorf("cache not sync")
+
 }
 
-func (m *manager) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
-	return reconcile.Result{}, nil
+// Close stops the watches of kinds and KubeWatch resources and flushes the
+// outputs of the manager.
+func (m *manager) Close() {
+	m.stopKubeWatches()
+	m.stopKinds()
+	if m.outputs != nil {
+		m.outputs.close()
+	}
 }
 
-func (m *manager) Start(ctx context.Context) error {
-	go m.mgr.Start(ctx)
-	if ok := m.mgr.GetCache().WaitForCacheSync(ctx); ok {
+// Done returns a channel closed when the user quits the interactive output,
+// nil before the outputs are opened or for other outputs.
+func (m *manager) Done() <-chan struct{} {
+	if m.outputs == nil {
 		return nil
 	}
-	return fmt.Errorf("cache not sync")
+	return output.Done(m.display)
+}
 
+// readyCheck reports ready once the cache of the manager and the caches of
+// all watched kinds are synced. Only the leader watches kinds, so a standby
+// is ready with the cache of the manager.
+func (m *manager) readyCheck(_ *http.Request) error {
+	m.Lock()
+	defer m.Unlock()
+	if !m.synced {
+		return fmt.Errorf("cache not synced")
+	}
+	if names := m.unsyncedKinds(); len(names) > 0 {
+		return fmt.Errorf("cache of kinds %s not synced", strings.Join(names, ","))
+	}
+	return nil
 }
 
 func (m *manager) listObjects(ctx context.Context, objList client.ObjectList, namespace string) error {
 	opts := []client.ListOption{}
-	if namespace != "" {
+	if namespace != "" && m.matchers.namespaces.mode == MatchExact && !strings.Contains(namespace, Split) {
 		opts = append(opts, client.InNamespace(namespace))
 	}
+	if m.labelSelector != nil && !m.labelSelector.Empty() {
+		opts = append(opts, client.MatchingLabelsSelector{Selector: m.labelSelector})
+	}
+	if fs := serverFieldSelector(m.fieldSelector); fs != nil {
+		opts = append(opts, client.MatchingFieldsSelector{Selector: fs})
+	}
 	if err := m.List(ctx, objList, opts...); err != nil {
 		return err
 	}
@@ -431,9 +647,14 @@ func (m *manager) ListObject(ctx context.Context, objList client.ObjectList, nam
 		totalNum := items.Len()
 		for i := 0; i < totalNum; i++ {
 			item := items.Index(i)
-			obj := interface{}(item.Interface())
-			switch o := (obj).(type) {
-			case unstructured.Unstructured:
+			if o, ok := item.Addr().Interface().(client.Object); ok {
+				ns := o.GetNamespace()
+				if !m.matchers.namespaces.Match(ns) || m.matchers.excludeNamespaces.Exclude(ns) {
+					continue
+				}
+				if !matchSelectors(o, nil, m.fieldSelector) {
+					continue
+				}
 				result = append(result, o.GetName())
 				continue
 			}
@@ -541,10 +762,18 @@ func (m *manager) ListObjects(ctx context.Context, groupVersion string, objects
 }
 
 func (m *manager) getSchemeObject(ctx context.Context, groupVersion string, kind string) (*SchemeObject, error) {
-	for _, v := range m.objects {
-		if v.Name == kind || slices.Contains(v.ShortNames, kind) {
-			return &v, nil
-		}
+	m.Lock()
+	rs := make(Resources, 0, len(m.objects))
+	for _, o := range m.objects {
+		rs = append(rs, o)
+	}
+	m.Unlock()
+	o, ok, err := rs.Resolve(kind)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no kind %s/%s", groupVersion, kind)
 	}
-	return nil, fmt.Errorf("no kind %s/%s", groupVersion, kind)
+	return &o, nil
 }
@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	MatchExact  = "exact"
+	MatchPrefix = "prefix"
+	MatchGlob   = "glob"
+	MatchRegex  = "regex"
+)
+
+var MatchModes = []string{
+	MatchExact,
+	MatchPrefix,
+	MatchGlob,
+	MatchRegex,
+}
+
+// matcher matches names and namespaces against a list of patterns. An empty
+// matcher matches everything.
+type matcher struct {
+	mode     string
+	patterns []string
+	regexps  []*regexp.Regexp
+}
+
+func newMatcher(mode string, patterns []string) (*matcher, error) {
+	if mode == "" {
+		mode = MatchPrefix
+	}
+	m := &matcher{
+		mode: mode,
+	}
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		switch mode {
+		case MatchExact, MatchPrefix:
+		case MatchGlob:
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob pattern %q: %v", p, err)
+			}
+		case MatchRegex:
+			r, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex pattern %q: %v", p, err)
+			}
+			m.regexps = append(m.regexps, r)
+		default:
+			return nil, fmt.Errorf("unknown match mode %q, must be one of %s", mode, strings.Join(MatchModes, "|"))
+		}
+		m.patterns = append(m.patterns, p)
+	}
+	return m, nil
+}
+
+func (m *matcher) Empty() bool {
+	return m == nil || len(m.patterns) == 0
+}
+
+func (m *matcher) Match(s string) bool {
+	if m.Empty() {
+		return true
+	}
+	for i, p := range m.patterns {
+		var ok bool
+		switch m.mode {
+		case MatchExact:
+			ok = s == p
+		case MatchPrefix:
+			ok = strings.HasPrefix(s, p)
+		case MatchGlob:
+			ok, _ = path.Match(p, s)
+		case MatchRegex:
+			ok = m.regexps[i].MatchString(s)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Exclude reports whether s matches any of the patterns. An empty matcher
+// excludes nothing.
+func (m *matcher) Exclude(s string) bool {
+	return !m.Empty() && m.Match(s)
+}
+
+func splitList(s string) []string {
+	res := make([]string, 0)
+	for _, v := range strings.Split(s, Split) {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+package manager
+
+import "testing"
+
+func TestMatcher(t *testing.T) {
+	type args struct {
+		mode     string
+		patterns []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		match   map[string]bool
+		wantErr bool
+	}{
+		{
+			name:  "empty",
+			args:  args{mode: MatchExact},
+			match: map[string]bool{"": true, "kube-system": true},
+		},
+		{
+			name:  "exact",
+			args:  args{mode: MatchExact, patterns: []string{"kube-system", "default"}},
+			match: map[string]bool{"kube-system": true, "default": true, "kube-public": false},
+		},
+		{
+			name:  "prefix",
+			args:  args{mode: MatchPrefix, patterns: []string{"kube"}},
+			match: map[string]bool{"kube-system": true, "kube-public": true, "my-kube-app": false},
+		},
+		{
+			name:  "glob",
+			args:  args{mode: MatchGlob, patterns: []string{"*-kube-*"}},
+			match: map[string]bool{"kube-system": false, "my-kube-app": true},
+		},
+		{
+			name:  "regex",
+			args:  args{mode: MatchRegex, patterns: []string{"^kube-(system|public)$"}},
+			match: map[string]bool{"kube-system": true, "kube-node-lease": false},
+		},
+		{
+			name:    "invalid regex",
+			args:    args{mode: MatchRegex, patterns: []string{"("}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			args:    args{mode: "contains", patterns: []string{"kube"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newMatcher(tt.args.mode, tt.args.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newMatcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for s, want := range tt.match {
+				if got := m.Match(s); got != want {
+					t.Errorf("Match(%q) = %v, want %v", s, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetMatchersDefault(t *testing.T) {
+	ms, err := Config{Namespace: "kube", Names: []string{"core"}, ExcludeNamespaces: "kube-public"}.getMatchers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ns, want := range map[string]bool{"kube": true, "kube-system": false, "my-kube": false} {
+		if got := ms.namespaces.Match(ns); got != want {
+			t.Errorf("namespace %q matched = %v, want %v", ns, got, want)
+		}
+	}
+	if ms.excludeNamespaces.Exclude("kube-public-2") {
+		t.Errorf("excluded namespaces match by prefix")
+	}
+	if !ms.names.Match("coredns") {
+		t.Errorf("names don't match by prefix")
+	}
+	ms, err = Config{Namespace: "kube", MatchMode: MatchPrefix}.getMatchers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ms.namespaces.Match("kube-system") {
+		t.Errorf("namespaces don't match by prefix with --match prefix")
+	}
+}
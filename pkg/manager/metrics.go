@@ -0,0 +1,257 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/metrics"
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+func (m *manager) objectLabels(obj client.Object) []string {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return []string{
+		m.cluster,
+		gvk.Group,
+		gvk.Version,
+		gvk.Kind,
+		obj.GetNamespace(),
+	}
+}
+
+func (m *manager) objectKey(obj client.Object) string {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	return fmt.Sprintf("%s/%s/%s/%s", m.cluster, gvk.GroupKind(), obj.GetNamespace(), obj.GetName())
+}
+
+// observeEvent counts the event and, for deletes, drops all metrics of the
+// object.
+func (m *manager) observeEvent(event string, obj client.Object) {
+	metrics.GetMetricsEvents().WithLabelValues(append(m.objectLabels(obj), event)...).Inc()
+	if event != "delete" {
+		return
+	}
+	m.rules.Delete(m.cluster, obj)
+	m.Lock()
+	delete(m.lastChanges, m.objectKey(obj))
+	m.Unlock()
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	labels := prometheus.Labels{
+		"cluster":   m.cluster,
+		"group":     gvk.Group,
+		"version":   gvk.Version,
+		"kind":      gvk.Kind,
+		"namespace": obj.GetNamespace(),
+		"name":      obj.GetName(),
+	}
+	for _, metr := range []*prometheus.GaugeVec{
+		metrics.GetMetricsFieldValues(),
+		metrics.GetMetricsFieldInfo(),
+		metrics.GetMetricsPluginValues(),
+		metrics.GetMetricsComputedValues(),
+	} {
+		mm, err := metr.CurryWith(labels)
+		if err != nil {
+			m.log(obj).Info("deleted object metrics", "err", err)
+			continue
+		}
+		mm.Reset()
+	}
+}
+
+// observeChanges updates the metrics of the changed fields of an object.
+func (m *manager) observeChanges(t time.Time, obj client.Object, changes []output.Change, config Config) {
+	if len(changes) == 0 {
+		return
+	}
+	key := m.objectKey(obj)
+	m.Lock()
+	last, ok := m.lastChanges[key]
+	m.lastChanges[key] = t
+	m.Unlock()
+	if ok && t.After(last) {
+		metrics.GetMetricsChangeInterval().WithLabelValues(m.objectLabels(obj)...).Observe(t.Sub(last).Seconds())
+	}
+	for _, c := range changes {
+		metrics.GetMetricsFieldChanges().WithLabelValues(append(m.objectLabels(obj), c.Path)...).Inc()
+		labels := append(m.objectLabels(obj), obj.GetName(), c.Path)
+		setFieldValue(labels, c.From, c.To, config.fieldInfo(obj, c.Path))
+	}
+}
+
+// maxInfoValue is the maximum length of the value label of field_info.
+const maxInfoValue = 64
+
+// explicitPaths reports whether the paths of the config are selected
+// explicitly by a path prefix or template, rather than all paths.
+func (c Config) explicitPaths() bool {
+	return c.PathPrefix != "" || c.PathTemplate != ""
+}
+
+// fieldInfo reports whether the value of a path may be exported as label of
+// field_info. Only explicitly selected paths are, as values like uids,
+// images or env values make for unbounded series, and never annotations or
+// the data of secrets and config maps, as the metrics are served without
+// authentication.
+func (c Config) fieldInfo(obj client.Object, path string) bool {
+	if !c.explicitPaths() {
+		return false
+	}
+	switch obj.GetObjectKind().GroupVersionKind().GroupKind().String() {
+	case "Secret", "ConfigMap":
+		return false
+	}
+	for _, s := range strings.FieldsFunc(c.PathPrefix+Split+path, func(r rune) bool {
+		return r == '/' || r == ','
+	}) {
+		if strings.EqualFold(s, "annotations") {
+			return false
+		}
+	}
+	return true
+}
+
+// infoValue returns the value label of field_info, cut to maxInfoValue.
+func infoValue(v interface{}) string {
+	s := []rune(fmt.Sprint(v))
+	if len(s) > maxInfoValue {
+		return string(s[:maxInfoValue-1]) + "~"
+	}
+	return string(s)
+}
+
+// observePlugins sets the metric values plugins extract from an object.
+func (m *manager) observePlugins(obj client.Object) {
+	for _, s := range hooksFor(obj).Metrics(obj) {
+		labels := append(m.objectLabels(obj), obj.GetName(), s.Name)
+		metrics.GetMetricsPluginValues().WithLabelValues(labels...).Set(s.Value)
+	}
+}
+
+// observeComputed sets the numeric and bool values computed from an object.
+func (m *manager) observeComputed(obj client.Object, values []interface{}) {
+	for i, name := range m.scripts.Names() {
+		labels := append(m.objectLabels(obj), obj.GetName(), name)
+		if v, ok := numericValue(values[i]); ok {
+			metrics.GetMetricsComputedValues().WithLabelValues(labels...).Set(v)
+		} else {
+			metrics.GetMetricsComputedValues().DeleteLabelValues(labels...)
+		}
+	}
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch vv := v.(type) {
+	case int64:
+		return float64(vv), true
+	case float64:
+		return vv, true
+	case bool:
+		if vv {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// observeSnapshot sets the metrics of the rules and of the fields selected
+// by the path prefix or template of an object, so they are populated for
+// existing objects on start and for new objects before their first change.
+func (m *manager) observeSnapshot(obj client.Object, config Config) {
+	if err := m.rules.Observe(m.cluster, obj); err != nil {
+		m.log(obj).Error(err, "failed to observe metric rules")
+	}
+	m.observePlugins(obj)
+	m.observeComputed(obj, m.computeValues(obj))
+	if !config.explicitPaths() {
+		return
+	}
+	content, err := objectContent(obj)
+	if err != nil {
+		m.log(obj).Error(err, "failed to read object fields")
+		return
+	}
+	var prefix []string
+	if _, ok := obj.(*unstructured.Unstructured); ok {
+		// keep the paths in line with the field diff of unstructured objects
+		prefix = []string{"Object"}
+	}
+	walkFields(content, prefix, func(segments []string, v interface{}) {
+		path, ok := config.filterPath(segments)
+		if !ok {
+			return
+		}
+		if config.DiffFormat == DiffPatch {
+			path = pointerPath(segments[len(prefix):])
+		}
+		setFieldValue(append(m.objectLabels(obj), obj.GetName(), path), nil, v, config.fieldInfo(obj, path))
+	})
+}
+
+// walkFields calls fn with the path of every scalar field of v.
+func walkFields(v interface{}, segments []string, fn func(segments []string, v interface{})) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, f := range vv {
+			walkFields(f, append(segments[:len(segments):len(segments)], k), fn)
+		}
+	case []interface{}:
+		for i, f := range vv {
+			walkFields(f, append(segments[:len(segments):len(segments)], strconv.Itoa(i)), fn)
+		}
+	case nil:
+	default:
+		fn(segments, v)
+	}
+}
+
+// setFieldValue sets the field_values metric for number and bool values, and
+// if info is set the field_info metric for other scalar values. Strings are
+// never guessed to be numbers or quantities, metric rules should be used to
+// parse them.
+func setFieldValue(labels []string, from, to interface{}, info bool) {
+	metr := metrics.GetMetricsFieldValues()
+	infoMetr := metrics.GetMetricsFieldInfo()
+	if from != nil {
+		infoMetr.DeleteLabelValues(append(labels, infoValue(from))...)
+	}
+	if to == nil {
+		metr.DeleteLabelValues(labels...)
+		return
+	}
+	v, ok := fieldValue(to)
+	if !ok {
+		metr.DeleteLabelValues(labels...)
+		if info {
+			infoMetr.WithLabelValues(append(labels, infoValue(to))...).Set(1)
+		}
+		return
+	}
+	metr.WithLabelValues(labels...).Set(v)
+}
+
+// fieldValue converts number and bool values with the parsers of the metric
+// rules.
+func fieldValue(v interface{}) (float64, bool) {
+	var parser string
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		parser = metrics.ParserNumber
+	case reflect.Bool:
+		parser = metrics.ParserBool
+	default:
+		return 0, false
+	}
+	f, err := metrics.ParseValue(parser, v, nil)
+	return f, err == nil
+}
@@ -0,0 +1,103 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/nfyxhan/kubewatch/pkg/metrics"
+)
+
+func TestObserveSnapshot(t *testing.T) {
+	metrics.GetMetricsFieldValues().Reset()
+	defer metrics.GetMetricsFieldValues().Reset()
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "web",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"paused":   true,
+		},
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+			},
+			"ratio":   0.5,
+			"timeout": "5m",
+			"size":    "1k",
+		},
+	}}
+	m := &manager{cluster: "staging", lastChanges: make(map[string]time.Time)}
+	m.observeSnapshot(obj, Config{})
+	if n := testutil.CollectAndCount(metrics.GetMetricsFieldValues()); n != 0 {
+		t.Errorf("field_values has %d series without selected paths, want 0", n)
+	}
+	m.observeSnapshot(obj, Config{PathTemplate: "."})
+	labels := []string{"staging", "apps", "v1", "Deployment", "default", "web"}
+	tests := []struct {
+		path string
+		want float64
+	}{
+		{"spec/replicas", 3},
+		{"spec/paused", 1},
+		{"status/ratio", 0.5},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(metrics.GetMetricsFieldValues().WithLabelValues(append(labels, tt.path)...))
+		if got != tt.want {
+			t.Errorf("field_values{path=%q} = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	// strings are not guessed to be bools, numbers or quantities
+	if n := testutil.CollectAndCount(metrics.GetMetricsFieldValues()); n != 3 {
+		t.Errorf("field_values has %d series, want 3", n)
+	}
+}
+
+func TestObserveFieldInfo(t *testing.T) {
+	metrics.GetMetricsFieldInfo().Reset()
+	defer metrics.GetMetricsFieldInfo().Reset()
+	long := strings.Repeat("x", 100)
+	obj := func(kind string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name":        "web",
+				"namespace":   "default",
+				"annotations": map[string]interface{}{"note": "secret"},
+			},
+			"data":   map[string]interface{}{"password": "c2VjcmV0"},
+			"status": map[string]interface{}{"phase": "Running", "message": long},
+		}}
+	}
+	m := &manager{cluster: "staging", lastChanges: make(map[string]time.Time)}
+	m.observeSnapshot(obj("Pod"), Config{})
+	m.observeSnapshot(obj("Secret"), Config{PathTemplate: "."})
+	m.observeSnapshot(obj("ConfigMap"), Config{PathTemplate: "."})
+	m.observeSnapshot(obj("Pod"), Config{PathPrefix: "Object,metadata,annotations"})
+	if n := testutil.CollectAndCount(metrics.GetMetricsFieldInfo()); n != 0 {
+		t.Fatalf("field_info has %d series of unselected, secret or annotation fields, want 0", n)
+	}
+	m.observeSnapshot(obj("Pod"), Config{PathTemplate: "^Object/(status|data)/"})
+	labels := []string{"staging", "", "v1", "Pod", "default", "web"}
+	for _, l := range [][]string{
+		{"status/phase", "Running"},
+		{"status/message", strings.Repeat("x", maxInfoValue-1) + "~"},
+		{"data/password", "c2VjcmV0"},
+	} {
+		if got := testutil.ToFloat64(metrics.GetMetricsFieldInfo().WithLabelValues(append(labels, l...)...)); got != 1 {
+			t.Errorf("field_info{path=%q} = %v, want 1", l[0], got)
+		}
+	}
+	if n := testutil.CollectAndCount(metrics.GetMetricsFieldInfo()); n != 3 {
+		t.Errorf("field_info has %d series, want 3", n)
+	}
+}
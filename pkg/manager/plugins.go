@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/plugins"
+)
+
+// hooksFor returns the hooks of the plugins for the kind of an object.
+func hooksFor(obj client.Object) plugins.Hooks {
+	return plugins.HooksFor(obj.GetObjectKind().GroupVersionKind())
+}
+
+// typedResources returns the resources with the typed objects of plugins
+// where they register the kind, and unstructured ones otherwise. Typed
+// objects are diffed by their Go field names, e.g. Status,ReadyReplicas.
+func typedResources(rs Resources, kinds map[schema.GroupVersionKind]plugins.Object) Resources {
+	if len(kinds) == 0 {
+		return rs
+	}
+	typed := make(Resources, 0, len(rs))
+	for _, o := range rs {
+		gvk := o.GVK()
+		p, ok := kinds[gvk]
+		if !ok {
+			typed = append(typed, o)
+			continue
+		}
+		obj := p.Object.DeepCopyObject().(client.Object)
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		list := p.ObjectList.DeepCopyObject().(client.ObjectList)
+		list.GetObjectKind().SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		o.Object, o.ObjectList = obj, list
+		o.ShortNames = append([]string(nil), o.ShortNames...)
+		if p.ShortName != "" && !slices.Contains(o.ShortNames, p.ShortName) {
+			o.ShortNames = append(o.ShortNames, p.ShortName)
+		}
+		typed = append(typed, o)
+	}
+	return typed
+}
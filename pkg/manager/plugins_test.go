@@ -0,0 +1,39 @@
+package manager
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nfyxhan/kubewatch/pkg/plugins"
+)
+
+func TestTypedResources(t *testing.T) {
+	kinds := map[schema.GroupVersionKind]plugins.Object{
+		appsv1.SchemeGroupVersion.WithKind("Deployment"): {
+			Name:       "deployment",
+			ShortName:  "dp",
+			Object:     &appsv1.Deployment{},
+			ObjectList: &appsv1.DeploymentList{},
+		},
+	}
+	rs := typedResources(testResources, kinds)
+	deploy, ok, err := rs.Resolve("dp")
+	if err != nil || !ok {
+		t.Fatalf("Resolve(dp) = %v, %v, want the short name of the plugin", ok, err)
+	}
+	if _, ok := deploy.Object.(*appsv1.Deployment); !ok || deploy.GVK().Kind != "Deployment" {
+		t.Errorf("object = %#v, want a typed deployment", deploy.Object)
+	}
+	if _, ok := deploy.ObjectList.(*appsv1.DeploymentList); !ok || deploy.ObjectList.GetObjectKind().GroupVersionKind().Kind != "DeploymentList" {
+		t.Errorf("list = %#v, want a typed deployment list", deploy.ObjectList)
+	}
+	sts, _, _ := rs.Resolve("sts")
+	if _, ok := sts.Object.(*appsv1.StatefulSet); ok {
+		t.Errorf("statefulsets are typed without a plugin")
+	}
+	if len(testResources[3].ShortNames) != 1 {
+		t.Errorf("short names of the resources are changed")
+	}
+}
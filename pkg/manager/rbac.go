@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"context"
+	"sort"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+// PolicyRules returns the RBAC rules needed to watch the kinds of the config
+// and the namespaces the manager always watches.
+func PolicyRules(ctx context.Context, config Config, cli SchemeClient) ([]rbacv1.PolicyRule, error) {
+	objects := make(map[string]SchemeObject)
+	// KubeWatch resources may be the only watches
+	if !config.KubeWatches || config.Objects != "" || config.GroupVersion != "" {
+		rs, err := cli.GetResources(ctx, config.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+		if objects, err = config.selectObjects(rs); err != nil {
+			return nil, err
+		}
+	}
+	groups := map[string]map[string]struct{}{
+		"": {"namespaces": {}},
+	}
+	for _, o := range objects {
+		group := o.Object.GetObjectKind().GroupVersionKind().Group
+		if groups[group] == nil {
+			groups[group] = make(map[string]struct{})
+		}
+		groups[group][o.Resource] = struct{}{}
+	}
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Strings(names)
+	rules := make([]rbacv1.PolicyRule, 0, len(groups))
+	for _, g := range names {
+		resources := make([]string, 0, len(groups[g]))
+		for r := range groups[g] {
+			resources = append(resources, r)
+		}
+		sort.Strings(resources)
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{g},
+			Resources: resources,
+			Verbs:     []string{"get", "list", "watch"},
+		})
+	}
+	return rules, nil
+}
@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nfyxhan/kubewatch/pkg/journal"
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// Replay feeds the events of a journal written with Config.Record through the
+// same filters and output as a live watch. Kinds are resolved like a live
+// watch from the resources recorded with the events, as no discovery is
+// available offline.
+func Replay(ctx context.Context, config Config, path string) error {
+	m, err := newManager(config)
+	if err != nil {
+		return err
+	}
+	kinds, err := replayKinds(path, config)
+	if err != nil {
+		return err
+	}
+	// never append a replay to a journal
+	config.Record = ""
+	if m.outputs, err = newOutputs(ctx, config); err != nil {
+		return err
+	}
+	defer m.close()
+	reader, err := journal.NewReader(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		select {
+		case <-output.Done(m.display):
+			// the user quit the interactive output
+			return nil
+		default:
+		}
+		e, err := reader.Next()
+		if err == io.EOF {
+			// keep the interactive output open for browsing the replay
+			output.Wait(m.display)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read journal %s: %v", path, err)
+		}
+		if m.started.IsZero() {
+			// the journal starts with the objects existing at record time
+			m.started = e.Time
+		}
+		m.cluster = e.Cluster
+		obj := e.Object()
+		if obj == nil || !kinds[obj.GroupVersionKind()] {
+			continue
+		}
+		switch e.Type {
+		case journal.TypeCreate:
+			if e.New == nil {
+				continue
+			}
+			m.OnCreate(ctx, e.Time, e.New, config)
+		case journal.TypeUpdate:
+			if e.Old == nil || e.New == nil {
+				continue
+			}
+			m.OnUpdate(ctx, e.Time, e.Old, e.New, config)
+		case journal.TypeDelete:
+			if e.Old == nil {
+				continue
+			}
+			m.OnDelete(ctx, e.Time, e.Old, config)
+		}
+	}
+}
+
+// replayKinds returns the kinds of the journal selected by the config. The
+// kinds of the config are resolved in the resources of the journal entries,
+// entries recorded without their resource only match by kind.
+func replayKinds(path string, config Config) (map[schema.GroupVersionKind]bool, error) {
+	reader, err := journal.NewReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var rs Resources
+	seen := make(map[schema.GroupVersionKind]bool)
+	for {
+		e, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read journal %s: %v", path, err)
+		}
+		obj := e.Object()
+		if obj == nil || seen[obj.GroupVersionKind()] {
+			continue
+		}
+		seen[obj.GroupVersionKind()] = true
+		rs = append(rs, replayResource(obj, e.Resource))
+	}
+	selected := make(map[schema.GroupVersionKind]bool)
+	kinds := splitList(config.Objects)
+	if len(kinds) == 0 {
+		for _, o := range rs {
+			selected[o.GVK()] = true
+		}
+	}
+	for _, k := range kinds {
+		o, ok, err := rs.Resolve(k)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			selected[o.GVK()] = true
+		}
+	}
+	for _, k := range splitList(config.ExcludeObjects) {
+		o, ok, err := rs.Resolve(k)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			delete(selected, o.GVK())
+		}
+	}
+	if gv := config.GroupVersion; gv != "" {
+		for gvk := range selected {
+			if gvk.GroupVersion().String() != gv {
+				delete(selected, gvk)
+			}
+		}
+	}
+	return selected, nil
+}
+
+// replayResource returns the resource of a journal entry, named by its lower
+// case kind if it wasn't recorded.
+func replayResource(obj *unstructured.Unstructured, res *journal.Resource) SchemeObject {
+	o := SchemeObject{
+		Name:     strings.ToLower(obj.GetKind()),
+		Resource: strings.ToLower(obj.GetKind()),
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": obj.GetAPIVersion(),
+			"kind":       obj.GetKind(),
+		}},
+	}
+	if res != nil {
+		o.Resource = res.Name
+		o.ShortNames = res.ShortNames
+		if res.SingularName != "" {
+			o.Name = res.SingularName
+		}
+	}
+	return o
+}
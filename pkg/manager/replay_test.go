@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/nfyxhan/kubewatch/pkg/journal"
+)
+
+func TestReplayKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	r, err := journal.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(apiVersion, kind string, res *journal.Resource) {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		}}
+		if err := r.Record(time.Now(), "", journal.TypeCreate, res, nil, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("apps/v1", "Deployment", &journal.Resource{Name: "deployments", SingularName: "deployment", ShortNames: []string{"deploy"}})
+	record("apps/v1", "StatefulSet", &journal.Resource{Name: "statefulsets", SingularName: "statefulset", ShortNames: []string{"sts"}})
+	// recorded before the resources were kept
+	record("v1", "Pod", nil)
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{name: "all", config: Config{}, want: "Deployment,Pod,StatefulSet"},
+		{name: "short name", config: Config{Objects: "deploy"}, want: "Deployment"},
+		{name: "qualified", config: Config{Objects: "deployments.apps,sts"}, want: "Deployment,StatefulSet"},
+		{name: "kind", config: Config{Objects: "pod"}, want: "Pod"},
+		{name: "exclude", config: Config{ExcludeObjects: "deploy,pod"}, want: "StatefulSet"},
+		{name: "group version", config: Config{GroupVersion: "apps/v1", ExcludeObjects: "sts"}, want: "Deployment"},
+		{name: "unknown", config: Config{Objects: "svc"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kinds, err := replayKinds(path, tt.config)
+			if err != nil {
+				t.Fatalf("replayKinds() error = %v", err)
+			}
+			var got []string
+			for gvk := range kinds {
+				got = append(got, gvk.Kind)
+			}
+			sort.Strings(got)
+			if s := strings.Join(got, ","); s != tt.want {
+				t.Errorf("replayKinds() = %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
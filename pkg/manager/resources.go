@@ -0,0 +1,152 @@
+package manager
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/strings/slices"
+)
+
+// GVK returns the group, version and kind of the object.
+func (o SchemeObject) GVK() schema.GroupVersionKind {
+	return o.Object.GetObjectKind().GroupVersionKind()
+}
+
+// QualifiedName returns the plural resource name with its group like
+// kubectl, e.g. deployments.apps, or pods for the core group.
+func (o SchemeObject) QualifiedName() string {
+	if g := o.GVK().Group; g != "" {
+		return o.Resource + "." + g
+	}
+	return o.Resource
+}
+
+// Watchable reports whether the resource supports list and watch, true if
+// its verbs are unknown.
+func (o SchemeObject) Watchable() bool {
+	return len(o.Verbs) == 0 || slices.Contains(o.Verbs, "list") && slices.Contains(o.Verbs, "watch")
+}
+
+// HasSubresource reports whether the resource has the subresource, e.g.
+// status or scale.
+func (o SchemeObject) HasSubresource(name string) bool {
+	return slices.Contains(o.Subresources, name)
+}
+
+// matches reports whether name is the plural, singular, short name or kind
+// of the resource.
+func (o SchemeObject) matches(name string) bool {
+	if strings.EqualFold(name, o.Resource) || strings.EqualFold(name, o.Name) || strings.EqualFold(name, o.GVK().Kind) {
+		return true
+	}
+	for _, s := range o.ShortNames {
+		if strings.EqualFold(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resources are the watchable resources of one or all group versions.
+type Resources []SchemeObject
+
+var versionPattern = regexp.MustCompile(`^v\d+((alpha|beta)\d+)?$`)
+
+// Resolve returns the resource of a kind argument like kubectl resolves it:
+// resource.version.group, resource.group or a plural, singular, short name
+// or kind, e.g. deployments.apps, Deployment.v1.apps or deploy. It returns
+// false if there is no such resource, and an error if the argument matches
+// resources of several groups.
+func (rs Resources) Resolve(arg string) (SchemeObject, bool, error) {
+	type try struct{ name, version, group string }
+	var tries []try
+	if parts := strings.SplitN(arg, ".", 3); len(parts) == 3 && versionPattern.MatchString(parts[1]) {
+		tries = append(tries, try{parts[0], parts[1], parts[2]})
+	}
+	if parts := strings.SplitN(arg, ".", 2); len(parts) == 2 {
+		tries = append(tries, try{parts[0], "", parts[1]})
+	}
+	tries = append(tries, try{name: arg})
+	for _, t := range tries {
+		var matches []SchemeObject
+		for _, o := range rs {
+			gvk := o.GVK()
+			if t.group != "" && gvk.Group != t.group || t.version != "" && gvk.Version != t.version {
+				continue
+			}
+			if o.matches(t.name) {
+				matches = append(matches, o)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			continue
+		case 1:
+			return matches[0], true, nil
+		}
+		names := make([]string, 0, len(matches))
+		for _, o := range matches {
+			names = append(names, o.QualifiedName())
+		}
+		sort.Strings(names)
+		return SchemeObject{}, false, fmt.Errorf("kind %q is ambiguous, use one of %s", arg, strings.Join(names, ", "))
+	}
+	return SchemeObject{}, false, nil
+}
+
+// Unknown returns the kinds of the list which aren't resolved.
+func (rs Resources) Unknown(kinds []string) []string {
+	var unknown []string
+	for _, k := range kinds {
+		if _, ok, err := rs.Resolve(k); !ok && err == nil {
+			unknown = append(unknown, k)
+		}
+	}
+	return unknown
+}
+
+// selectObjects returns the resources of the kinds of the config keyed by
+// their qualified names, all watchable resources of the group version if no
+// kinds are given, without the excluded kinds. Kinds which aren't found are
+// skipped, they may be installed later, kinds which can't be watched are an
+// error.
+func (c Config) selectObjects(rs Resources) (map[string]SchemeObject, error) {
+	objects := make(map[string]SchemeObject)
+	kinds := splitList(c.Objects)
+	if len(kinds) == 0 {
+		if c.GroupVersion == "" {
+			return nil, fmt.Errorf("no kinds selected, give kinds or a group version")
+		}
+		for _, o := range rs {
+			if c.checkWatchable(o) == nil {
+				objects[o.QualifiedName()] = o
+			}
+		}
+	}
+	for _, k := range kinds {
+		o, ok, err := rs.Resolve(k)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if err := c.checkWatchable(o); err != nil {
+			return nil, err
+		}
+		objects[o.QualifiedName()] = o
+	}
+	for _, k := range splitList(c.ExcludeObjects) {
+		o, ok, err := rs.Resolve(k)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			delete(objects, o.QualifiedName())
+		}
+	}
+	return objects, nil
+}
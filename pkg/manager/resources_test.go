@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testResource(groupVersion, kind, resource string, shortNames ...string) SchemeObject {
+	return SchemeObject{
+		Name:       strings.ToLower(kind),
+		ShortNames: shortNames,
+		Resource:   resource,
+		Object: &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": groupVersion,
+			"kind":       kind,
+		}},
+	}
+}
+
+var testResources = Resources{
+	testResource("v1", "Pod", "pods", "po"),
+	testResource("v1", "Event", "events", "ev"),
+	testResource("events.k8s.io/v1", "Event", "events", "ev"),
+	testResource("apps/v1", "Deployment", "deployments", "deploy"),
+	testResource("apps/v1", "StatefulSet", "statefulsets", "sts"),
+	testResource("example.com/v1alpha1", "Deployment", "deployments"),
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		arg       string
+		want      string
+		ambiguous bool
+	}{
+		{arg: "po", want: "pods"},
+		{arg: "Pod", want: "pods"},
+		{arg: "pods", want: "pods"},
+		{arg: "sts", want: "statefulsets.apps"},
+		{arg: "deployments.apps", want: "deployments.apps"},
+		{arg: "deploy.apps", want: "deployments.apps"},
+		{arg: "Deployment.v1.apps", want: "deployments.apps"},
+		{arg: "deployment.v1alpha1.example.com", want: "deployments.example.com"},
+		{arg: "deployments.example.com", want: "deployments.example.com"},
+		{arg: "Deployment.v2.apps"},
+		{arg: "foo"},
+		{arg: "deployment", ambiguous: true},
+		{arg: "events", ambiguous: true},
+		{arg: "events.events.k8s.io", want: "events.events.k8s.io"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			o, ok, err := testResources.Resolve(tt.arg)
+			if (err != nil) != tt.ambiguous {
+				t.Fatalf("Resolve() error = %v, want ambiguous %v", err, tt.ambiguous)
+			}
+			var got string
+			if ok {
+				got = o.QualifiedName()
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		want    string
+		wantErr bool
+	}{
+		{name: "kinds of several groups", config: Config{Objects: "po,deploy.apps,foo"}, want: "deployments.apps,pods"},
+		{name: "excluded", config: Config{Objects: "po,sts", ExcludeObjects: "statefulsets.apps"}, want: "pods"},
+		{name: "all of the group version", config: Config{GroupVersion: "apps/v1"}, want: "deployments.apps,deployments.example.com,events,events.events.k8s.io,pods,statefulsets.apps"},
+		{name: "nothing selected", wantErr: true},
+		{name: "ambiguous", config: Config{Objects: "deployment"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := tt.config.selectObjects(testResources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectObjects() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for name := range objects {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if s := strings.Join(got, ","); s != tt.want {
+				t.Errorf("selectObjects() = %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectWatchable(t *testing.T) {
+	deploy := testResource("apps/v1", "Deployment", "deployments", "deploy")
+	deploy.Verbs = []string{"get", "list", "watch"}
+	deploy.Subresources = []string{"status", "scale"}
+	cm := testResource("v1", "ConfigMap", "configmaps", "cm")
+	cm.Verbs = []string{"get", "list", "watch"}
+	binding := testResource("v1", "Binding", "bindings")
+	binding.Verbs = []string{"create"}
+	rs := Resources{deploy, cm, binding}
+	tests := []struct {
+		name    string
+		config  Config
+		want    string
+		wantErr bool
+	}{
+		{name: "not watchable", config: Config{Objects: "bindings"}, wantErr: true},
+		{name: "not watchable skipped", config: Config{GroupVersion: "v1"}, want: "configmaps,deployments.apps"},
+		{name: "status", config: Config{Objects: "deploy", Scope: ScopeStatus}, want: "deployments.apps"},
+		{name: "no status subresource", config: Config{Objects: "cm", Scope: ScopeStatus}, wantErr: true},
+		{name: "no status subresource skipped", config: Config{GroupVersion: "v1", Scope: ScopeStatus}, want: "deployments.apps"},
+		{name: "spec", config: Config{Objects: "cm", Scope: ScopeSpec}, want: "configmaps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects, err := tt.config.selectObjects(rs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectObjects() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for name := range objects {
+				got = append(got, name)
+			}
+			sort.Strings(got)
+			if s := strings.Join(got, ","); s != tt.want {
+				t.Errorf("selectObjects() = %s, want %s", s, tt.want)
+			}
+		})
+	}
+}
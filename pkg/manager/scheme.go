@@ -1,127 +1,256 @@
 package manager
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
-	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/discovery"
+	diskcached "k8s.io/client-go/discovery/cached/disk"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/kubectl/pkg/rawhttp"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/scheme"
+
+	"github.com/nfyxhan/kubewatch/pkg/plugins"
+	"github.com/nfyxhan/kubewatch/pkg/utils"
 )
 
+// schemeClient discovers the groups and resources of a cluster per group
+// version. Aggregated discovery needs client-go 0.26 or later and is not
+// supported yet.
 type schemeClient struct {
 	config *rest.Config
+	once   sync.Once
+	err    error
+	// discovery is cached on disk for the TTL, stale is the same cache used
+	// regardless of its age when the API server can't be reached.
+	discovery discovery.CachedDiscoveryInterface
+	stale     discovery.CachedDiscoveryInterface
+	clientset kubernetes.Interface
+}
+
+// init creates the discovery clients and the clientset on first use, so
+// creating a scheme client never fails.
+func (c *schemeClient) init() error {
+	c.once.Do(func() {
+		dir := filepath.Join(utils.CacheDir, "discovery", cacheDirName(c.config.Host))
+		httpDir := filepath.Join(utils.CacheDir, "http")
+		if c.discovery, c.err = diskcached.NewCachedDiscoveryClientForConfig(c.config, dir, httpDir, utils.DiscoveryCacheTTL); c.err != nil {
+			return
+		}
+		if c.stale, c.err = diskcached.NewCachedDiscoveryClientForConfig(c.config, dir, "", staleCacheTTL); c.err != nil {
+			return
+		}
+		c.clientset, c.err = kubernetes.NewForConfig(c.config)
+	})
+	return c.err
+}
+
+// staleCacheTTL is the age up to which cached discovery is used when the API
+// server can't be reached.
+const staleCacheTTL = 30 * 24 * time.Hour
+
+// illegalCacheChars are replaced in the host of the cache directory like
+// kubectl does, so the cache is shared with it.
+var illegalCacheChars = regexp.MustCompile(`[^(\w/.)]`)
+
+func cacheDirName(host string) string {
+	host = strings.Replace(strings.Replace(host, "https://", "", 1), "http://", "", 1)
+	return illegalCacheChars.ReplaceAllString(host, "_")
 }
 
 func (c *schemeClient) GetRestConfig() *rest.Config {
 	return c.config
 }
 
+// Invalidate makes the next requests ask the API server again, e.g. to find
+// newly installed CRDs.
+func (c *schemeClient) Invalidate() {
+	if err := c.init(); err == nil {
+		c.discovery.Invalidate()
+	}
+}
+
 func (c *schemeClient) ListNamespace(ctx context.Context) ([]corev1.Namespace, error) {
-	path := "/api/v1/namespaces"
-	obj := &corev1.NamespaceList{}
-	if err := c.rawGet(ctx, path, obj); err != nil {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	list, err := c.clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
 		return nil, err
 	}
-	return obj.Items, nil
+	return list.Items, nil
 }
 
-func (c *schemeClient) GetObjectMap(ctx context.Context, group string) (map[string]SchemeObject, error) {
-	objects, err := c.listObjects(ctx, group)
-	if err != nil {
+// CanWatch asks the API server with a SubjectAccessReview whether the
+// ServiceAccount may list and watch the kind in its namespace.
+func (c *schemeClient) CanWatch(ctx context.Context, namespace, serviceAccount string, o SchemeObject) (bool, error) {
+	if err := c.init(); err != nil {
+		return false, err
+	}
+	for _, verb := range []string{"list", "watch"} {
+		review := &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				User:   fmt.Sprintf("system:serviceaccount:%s:%s", namespace, serviceAccount),
+				Groups: []string{"system:serviceaccounts", "system:serviceaccounts:" + namespace, "system:authenticated"},
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      verb,
+					Group:     o.GVK().Group,
+					Resource:  o.Resource,
+				},
+			},
+		}
+		res, err := c.clientset.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return false, err
+		}
+		if !res.Status.Allowed {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// GetResources returns the watchable resources of a group version, of the
+// preferred versions of all groups if it is empty.
+func (c *schemeClient) GetResources(ctx context.Context, groupVersion string) (Resources, error) {
+	var lists []*metav1.APIResourceList
+	if groupVersion == "" {
+		var err error
+		if lists, err = c.listPreferredResources(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		list, err := c.ListApiResources(ctx, groupVersion)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	var rs Resources
+	for _, list := range lists {
+		rs = append(rs, schemeObjects(list)...)
+	}
+	return typedResources(rs, plugins.Kinds()), nil
+}
+
+// listPreferredResources returns the resources of the preferred versions of
+// all groups, skipping groups which fail like an unavailable aggregated API.
+func (c *schemeClient) listPreferredResources(ctx context.Context) ([]*metav1.APIResourceList, error) {
+	if err := c.init(); err != nil {
 		return nil, err
 	}
-	var objectMap = make(map[string]SchemeObject)
-	for _, o := range objects {
-		name := o.Name
-		objectMap[name] = o
-		for _, shortName := range o.ShortNames {
-			objectMap[shortName] = o
+	lists, err := c.discovery.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		// the API server can't be reached, use the cache if there is one
+		stale, staleErr := c.stale.ServerPreferredResources()
+		if len(stale) == 0 {
+			return nil, err
 		}
+		lists, err = stale, staleErr
 	}
-	return objectMap, nil
+	if err != nil {
+		log.FromContext(ctx).Error(err, "skipping groups")
+	}
+	return lists, nil
 }
 
+// ListApiGroups returns all groups with all their served versions, the core
+// group with the name "".
 func (c *schemeClient) ListApiGroups(ctx context.Context) ([]metav1.APIGroup, error) {
-	path := "/apis"
-	obj := &metav1.APIGroupList{}
-	if err := c.rawGet(ctx, path, obj); err != nil {
+	if err := c.init(); err != nil {
 		return nil, err
 	}
-	groups := obj.Groups
-	corev1GroupVersion := metav1.GroupVersionForDiscovery{
-		GroupVersion: "v1",
-		Version:      "v1",
-	}
-	groups = append(groups, metav1.APIGroup{
-		Name: "v1",
-		Versions: []metav1.GroupVersionForDiscovery{
-			corev1GroupVersion,
-		},
-		PreferredVersion: corev1GroupVersion,
-	})
-	return groups, nil
+	list, err := c.discovery.ServerGroups()
+	if err != nil {
+		if list, err = c.stale.ServerGroups(); err != nil {
+			return nil, err
+		}
+	}
+	return list.Groups, nil
 }
 
+// ListApiResources returns the resources of a group version, e.g. apps/v1 or
+// v1 for the core group.
 func (c *schemeClient) ListApiResources(ctx context.Context, group string) (*metav1.APIResourceList, error) {
-	var path string
-	if group != "v1" {
-		path = fmt.Sprintf("/apis/%s", group)
-	} else {
-		path = "/api/v1"
+	if group == "" {
+		return &metav1.APIResourceList{}, nil
+	}
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	list, err := c.discovery.ServerResourcesForGroupVersion(group)
+	if err == nil {
+		return list, nil
 	}
-	obj := &metav1.APIResourceList{}
-	if err := c.rawGet(ctx, path, obj); err != nil {
+	if !apierrors.IsNotFound(err) {
+		// the API server can't be reached, use the cache if there is one
+		if list, staleErr := c.stale.ServerResourcesForGroupVersion(group); staleErr == nil {
+			return list, nil
+		}
 		return nil, err
 	}
-	return obj, nil
+	// the group version may be new since the cache was written
+	c.discovery.Invalidate()
+	return c.discovery.ServerResourcesForGroupVersion(group)
 }
 
+// AddToScheme registers all served versions of all groups.
 func (c *schemeClient) AddToScheme(ctx context.Context, scm *runtime.Scheme) error {
 	groups, err := c.ListApiGroups(ctx)
 	if err != nil {
 		return err
 	}
 	for _, group := range groups {
-		GroupVersion := schema.GroupVersion{
-			Group:   group.Name,
-			Version: group.PreferredVersion.Version,
-		}
-		SchemeBuilder := &scheme.Builder{
-			GroupVersion: GroupVersion,
-		}
-		if err := SchemeBuilder.AddToScheme(scm); err != nil {
-			return err
+		for _, v := range group.Versions {
+			SchemeBuilder := &scheme.Builder{
+				GroupVersion: schema.GroupVersion{
+					Group:   group.Name,
+					Version: v.Version,
+				},
+			}
+			if err := SchemeBuilder.AddToScheme(scm); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-func (c *schemeClient) listObjects(ctx context.Context, group string) ([]SchemeObject, error) {
-	apiResourceList, err := c.ListApiResources(ctx, group)
-	if err != nil {
-		return nil, err
+// schemeObjects returns the resources of a list with their subresources.
+func schemeObjects(list *metav1.APIResourceList) []SchemeObject {
+	subresources := make(map[string][]string)
+	for _, r := range list.APIResources {
+		if parts := strings.SplitN(r.Name, "/", 2); len(parts) == 2 {
+			subresources[parts[0]] = append(subresources[parts[0]], parts[1])
+		}
 	}
-	version := apiResourceList.GroupVersion
 	res := make([]SchemeObject, 0)
-	for _, r := range apiResourceList.APIResources {
+	for _, r := range list.APIResources {
+		if strings.Contains(r.Name, "/") {
+			continue
+		}
+		// built-in resources have no singular name before Kubernetes 1.27
 		name := r.SingularName
 		if name == "" {
-			continue
+			name = strings.ToLower(r.Kind)
 		}
 		obj := map[string]interface{}{
 			"kind":       r.Kind,
-			"apiVersion": version,
+			"apiVersion": list.GroupVersion,
 		}
 		if r.Version != "" {
 			v := r.Version
@@ -131,8 +260,12 @@ func (c *schemeClient) listObjects(ctx context.Context, group string) ([]SchemeO
 			obj["apiVersion"] = v
 		}
 		o := SchemeObject{
-			Name:       name,
-			ShortNames: r.ShortNames,
+			Name:         name,
+			ShortNames:   r.ShortNames,
+			Resource:     r.Name,
+			Verbs:        r.Verbs,
+			Subresources: subresources[r.Name],
+			Namespaced:   r.Namespaced,
 			Object: &unstructured.Unstructured{
 				Object: obj,
 			},
@@ -143,29 +276,5 @@ func (c *schemeClient) listObjects(ctx context.Context, group string) ([]SchemeO
 		}
 		res = append(res, o)
 	}
-	return res, nil
-}
-
-func (c *schemeClient) rawGet(ctx context.Context, path string, object interface{}) error {
-	config := c.config
-	config.APIPath = "api"
-	config.GroupVersion = &corev1.SchemeGroupVersion
-	config.NegotiatedSerializer = clientgoscheme.Codecs
-	cli, err := rest.RESTClientFor(config)
-	if err != nil {
-		return err
-	}
-	bf := bytes.NewBuffer(make([]byte, 0))
-	stream := genericclioptions.IOStreams{
-		In:     os.Stdin,
-		Out:    bf,
-		ErrOut: os.Stderr,
-	}
-	if err := rawhttp.RawGet(cli, stream, path); err != nil {
-		return err
-	}
-	if err := json.Unmarshal(bf.Bytes(), object); err != nil {
-		return err
-	}
-	return nil
+	return res
 }
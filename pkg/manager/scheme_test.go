@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/nfyxhan/kubewatch/pkg/utils"
+)
+
+func TestSchemeClientDiscovery(t *testing.T) {
+	responses := map[string]interface{}{
+		"/api": &metav1.APIVersions{Versions: []string{"v1"}},
+		"/apis": &metav1.APIGroupList{Groups: []metav1.APIGroup{{
+			Name: "apps",
+			Versions: []metav1.GroupVersionForDiscovery{
+				{GroupVersion: "apps/v1", Version: "v1"},
+				{GroupVersion: "apps/v1beta1", Version: "v1beta1"},
+			},
+			PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "apps/v1", Version: "v1"},
+		}}},
+		"/api/v1": &metav1.APIResourceList{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", Kind: "Pod", ShortNames: []string{"po"}, Verbs: []string{"list", "watch"}},
+				{Name: "bindings", Kind: "Binding", Verbs: []string{"create"}},
+			},
+		},
+		"/apis/apps/v1": &metav1.APIResourceList{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", ShortNames: []string{"deploy"}, Verbs: []string{"list", "watch"}},
+				{Name: "deployments/status", Kind: "Deployment"},
+			},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+	defer server.Close()
+	utils.CacheDir = t.TempDir()
+	utils.DiscoveryCacheTTL = 0
+	defer func() { utils.CacheDir, utils.DiscoveryCacheTTL = "", 0 }()
+
+	ctx := context.Background()
+	check := func(cli SchemeClient) {
+		t.Helper()
+		groups, err := cli.ListApiGroups(ctx)
+		if err != nil {
+			t.Fatalf("ListApiGroups() error = %v", err)
+		}
+		var versions int
+		for _, g := range groups {
+			versions += len(g.Versions)
+		}
+		if len(groups) != 2 || versions != 3 {
+			t.Errorf("ListApiGroups() = %+v, want core and apps with all versions", groups)
+		}
+		rs, err := cli.GetResources(ctx, "apps/v1")
+		if err != nil {
+			t.Fatalf("GetResources() error = %v", err)
+		}
+		if o, ok, _ := rs.Resolve("deploy"); !ok || o.QualifiedName() != "deployments.apps" || !o.HasSubresource("status") || len(rs) != 1 {
+			t.Errorf("GetResources() = %v", rs)
+		}
+		if rs, err = cli.GetResources(ctx, ""); err != nil {
+			t.Fatalf("GetResources() of all groups error = %v", err)
+		}
+		if _, ok, _ := rs.Resolve("po"); !ok || len(rs) != 3 {
+			t.Errorf("GetResources() of all groups = %v", rs)
+		}
+		if o, ok, _ := rs.Resolve("bindings"); !ok || o.Watchable() {
+			t.Errorf("GetResources() of all groups = %v, want bindings not watchable", rs)
+		}
+	}
+	check(NewSchemeClient(&rest.Config{Host: server.URL}))
+	// the cache is used when the API server is gone, regardless of its age
+	server.Close()
+	check(NewSchemeClient(&rest.Config{Host: server.URL, Timeout: time.Second}))
+}
+
+func TestCacheDirName(t *testing.T) {
+	if got, want := cacheDirName("https://10.0.0.1:6443"), "10.0.0.1_6443"; got != want {
+		t.Errorf("cacheDirName() = %s, want %s", got, want)
+	}
+}
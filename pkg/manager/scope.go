@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	ScopeAll    = "all"
+	ScopeStatus = "status"
+	ScopeSpec   = "spec"
+)
+
+// Scopes limit the reported changes to a part of the objects.
+var Scopes = []string{
+	ScopeAll,
+	ScopeStatus,
+	ScopeSpec,
+}
+
+func (c Config) checkScope() error {
+	if c.Scope != "" && !slices.Contains(Scopes, c.Scope) {
+		return fmt.Errorf("unknown scope %q, must be one of %s", c.Scope, strings.Join(Scopes, "|"))
+	}
+	return nil
+}
+
+// scopeField is the top level field changes are limited to, empty for all
+// fields.
+func (c Config) scopeField() string {
+	if c.Scope == ScopeAll {
+		return ""
+	}
+	return c.Scope
+}
+
+// inScope reports whether a path of the field diff is in the scope.
+func (c Config) inScope(segments []string) bool {
+	field := c.scopeField()
+	if field == "" {
+		return true
+	}
+	if len(segments) > 0 && segments[0] == "Object" {
+		segments = segments[1:]
+	}
+	return len(segments) > 0 && strings.EqualFold(segments[0], field)
+}
+
+// checkWatchable returns an error if the kind can't be watched, has no
+// status subresource although only status changes are watched, or is cluster
+// scoped although a KubeWatch only watches its namespace.
+func (c Config) checkWatchable(o SchemeObject) error {
+	if !o.Watchable() {
+		return fmt.Errorf("kind %s can't be watched, it only supports %s", o.QualifiedName(), strings.Join(o.Verbs, ","))
+	}
+	if c.kubeWatchNamespace != "" && !o.Namespaced {
+		return fmt.Errorf("kind %s is cluster scoped, a KubeWatch only watches its namespace %s", o.QualifiedName(), c.kubeWatchNamespace)
+	}
+	if c.Scope == ScopeStatus && !o.HasSubresource("status") {
+		return fmt.Errorf("kind %s has no status subresource, its status changes can't be watched alone", o.QualifiedName())
+	}
+	return nil
+}
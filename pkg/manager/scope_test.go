@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeFilterPath(t *testing.T) {
+	tests := []struct {
+		scope string
+		path  string
+		want  bool
+	}{
+		{scope: "", path: "Object,spec,replicas", want: true},
+		{scope: ScopeAll, path: "Object,status,replicas", want: true},
+		{scope: ScopeStatus, path: "Object,status,replicas", want: true},
+		{scope: ScopeStatus, path: "Object,spec,replicas", want: false},
+		{scope: ScopeSpec, path: "Object,spec,replicas", want: true},
+		{scope: ScopeSpec, path: "Object,status,replicas", want: false},
+		{scope: ScopeSpec, path: "Object,data,key", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scope+"/"+tt.path, func(t *testing.T) {
+			c := Config{Scope: tt.scope}
+			if _, got := c.filterPath(strings.Split(tt.path, ",")); got != tt.want {
+				t.Errorf("filterPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
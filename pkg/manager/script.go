@@ -0,0 +1,345 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// scriptMaxSteps limits the steps of a single evaluation, so a filter can't
+// stall the event handlers.
+const scriptMaxSteps = 100000
+
+var scriptOptions = &syntax.FileOptions{}
+
+// scriptBuiltins are predeclared in addition to the Starlark builtins.
+var scriptBuiltins = starlark.StringDict{
+	"get": starlark.NewBuiltin("get", scriptGet),
+}
+
+// scripts are the filter and computed values of a config, Starlark
+// expressions compiled once and evaluated for every event.
+type scripts struct {
+	filter  *starlark.Function
+	compute []computed
+}
+
+type computed struct {
+	name string
+	fn   *starlark.Function
+}
+
+// compileScripts compiles the filter, called with old and new, and the
+// computed values, called with obj.
+func (c Config) compileScripts() (*scripts, error) {
+	s := &scripts{}
+	if strings.TrimSpace(c.Filter) != "" {
+		fn, err := compileScript("filter", strings.TrimSpace(c.Filter), "old", "new")
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %v", err)
+		}
+		s.filter = fn
+	}
+	names := make(map[string]struct{})
+	for _, v := range c.Compute {
+		i := strings.Index(v, "=")
+		if i <= 0 || strings.TrimSpace(v[:i]) == "" {
+			return nil, fmt.Errorf("invalid compute %q, must be name=expression", v)
+		}
+		name := strings.TrimSpace(v[:i])
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("compute %s is given twice", name)
+		}
+		names[name] = struct{}{}
+		fn, err := compileScript(name, strings.TrimSpace(v[i+1:]), "obj")
+		if err != nil {
+			return nil, fmt.Errorf("invalid compute %s: %v", name, err)
+		}
+		s.compute = append(s.compute, computed{name: name, fn: fn})
+	}
+	return s, nil
+}
+
+// compileScript compiles an expression into a function of the given
+// parameters, so names are resolved before any event arrives.
+func compileScript(name, src string, params ...string) (*starlark.Function, error) {
+	expr, err := syntax.ParseExpr(name, src, 0)
+	if err != nil {
+		return nil, err
+	}
+	lambda := &syntax.LambdaExpr{Body: expr}
+	for _, p := range params {
+		lambda.Params = append(lambda.Params, &syntax.Ident{Name: p})
+	}
+	v, err := starlark.EvalExprOptions(scriptOptions, &starlark.Thread{Name: name}, lambda, scriptBuiltins)
+	if err != nil {
+		return nil, err
+	}
+	return v.(*starlark.Function), nil
+}
+
+func callScript(fn *starlark.Function, args ...starlark.Value) (starlark.Value, error) {
+	thread := &starlark.Thread{Name: fn.Name()}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	return starlark.Call(thread, fn, args, nil)
+}
+
+// Filter reports whether the update of the objects passes the filter.
+func (s *scripts) Filter(objOld, objNew client.Object) (bool, error) {
+	if s == nil || s.filter == nil {
+		return true, nil
+	}
+	vOld, err := scriptObjectOf(objOld)
+	if err != nil {
+		return true, err
+	}
+	vNew, err := scriptObjectOf(objNew)
+	if err != nil {
+		return true, err
+	}
+	v, err := callScript(s.filter, vOld, vNew)
+	if err != nil {
+		return true, err
+	}
+	return bool(v.Truth()), nil
+}
+
+// Names returns the names of the computed values.
+func (s *scripts) Names() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.compute))
+	for _, c := range s.compute {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// Compute returns the computed values of an object in the order of their
+// names, values which fail to evaluate are nil.
+func (s *scripts) Compute(obj client.Object) ([]interface{}, error) {
+	if s == nil || len(s.compute) == 0 {
+		return nil, nil
+	}
+	v, err := scriptObjectOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(s.compute))
+	var errs []string
+	for i, c := range s.compute {
+		res, err := callScript(c.fn, v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", c.name, err))
+			continue
+		}
+		values[i] = goValue(res)
+	}
+	if len(errs) > 0 {
+		return values, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return values, nil
+}
+
+func scriptObjectOf(obj client.Object) (starlark.Value, error) {
+	if obj == nil {
+		return starlark.None, nil
+	}
+	content, err := objectContent(obj)
+	if err != nil {
+		return nil, err
+	}
+	return scriptObject(content), nil
+}
+
+// scriptObject exposes a map of an object to scripts, its fields are read
+// as attributes, missing ones are None, or by index, e.g. for labels.
+type scriptObject map[string]interface{}
+
+var (
+	_ starlark.HasAttrs = scriptObject(nil)
+	_ starlark.Mapping  = scriptObject(nil)
+	_ starlark.Sequence = scriptObject(nil)
+)
+
+func (o scriptObject) String() string {
+	b, err := json.Marshal(map[string]interface{}(o))
+	if err != nil {
+		return fmt.Sprint(map[string]interface{}(o))
+	}
+	return string(b)
+}
+
+func (o scriptObject) Type() string         { return "object" }
+func (o scriptObject) Freeze()              {}
+func (o scriptObject) Truth() starlark.Bool { return len(o) > 0 }
+func (o scriptObject) Len() int             { return len(o) }
+
+func (o scriptObject) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: object")
+}
+
+func (o scriptObject) Attr(name string) (starlark.Value, error) {
+	return scriptValue(o[name]), nil
+}
+
+func (o scriptObject) AttrNames() []string {
+	names := make([]string, 0, len(o))
+	for k := range o {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (o scriptObject) Get(k starlark.Value) (starlark.Value, bool, error) {
+	key, ok := starlark.AsString(k)
+	if !ok {
+		return nil, false, fmt.Errorf("object keys are strings, got %s", k.Type())
+	}
+	v, ok := o[key]
+	if !ok {
+		return nil, false, nil
+	}
+	return scriptValue(v), true, nil
+}
+
+func (o scriptObject) Iterate() starlark.Iterator {
+	names := o.AttrNames()
+	keys := make(starlark.Tuple, 0, len(names))
+	for _, k := range names {
+		keys = append(keys, starlark.String(k))
+	}
+	return keys.Iterate()
+}
+
+// scriptValue converts a field of an object to a Starlark value.
+func scriptValue(v interface{}) starlark.Value {
+	switch vv := v.(type) {
+	case nil:
+		return starlark.None
+	case bool:
+		return starlark.Bool(vv)
+	case string:
+		return starlark.String(vv)
+	case int64:
+		return starlark.MakeInt64(vv)
+	case int:
+		return starlark.MakeInt(vv)
+	case int32:
+		return starlark.MakeInt64(int64(vv))
+	case float64:
+		return starlark.Float(vv)
+	case map[string]interface{}:
+		return scriptObject(vv)
+	case []interface{}:
+		items := make([]starlark.Value, 0, len(vv))
+		for _, item := range vv {
+			items = append(items, scriptValue(item))
+		}
+		l := starlark.NewList(items)
+		l.Freeze()
+		return l
+	}
+	return starlark.String(fmt.Sprint(v))
+}
+
+// goValue converts the result of a script to a value of a change.
+func goValue(v starlark.Value) interface{} {
+	switch vv := v.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		return bool(vv)
+	case starlark.Int:
+		if i, ok := vv.Int64(); ok {
+			return i
+		}
+		return float64(vv.Float())
+	case starlark.Float:
+		return float64(vv)
+	case starlark.String:
+		return string(vv)
+	}
+	return v.String()
+}
+
+// scriptGet is get(x, path, default=None), the value of a dotted path of x,
+// or default if a part of the path is missing.
+func scriptGet(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x starlark.Value
+	var path string
+	var def starlark.Value = starlark.None
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "x", &x, "path", &path, "default?", &def); err != nil {
+		return nil, err
+	}
+	var v interface{}
+	switch xx := x.(type) {
+	case starlark.NoneType:
+		return def, nil
+	case scriptObject:
+		v = map[string]interface{}(xx)
+	default:
+		return nil, fmt.Errorf("%s: got %s, want object", b.Name(), x.Type())
+	}
+	if v = lookupPath(v, strings.Split(path, ".")); v == nil {
+		return def, nil
+	}
+	return scriptValue(v), nil
+}
+
+// filterScript evaluates the filter of the manager on an update, it's kept
+// if the filter fails.
+func (m *manager) filterScript(objOld, objNew client.Object) bool {
+	ok, err := m.scripts.Filter(objOld, objNew)
+	if err != nil {
+		m.log(objNew).Error(err, "failed to evaluate filter")
+	}
+	return ok
+}
+
+func (m *manager) computeValues(obj client.Object) []interface{} {
+	values, err := m.scripts.Compute(obj)
+	if err != nil {
+		m.log(obj).Error(err, "failed to compute values")
+	}
+	if values == nil {
+		values = make([]interface{}, len(m.scripts.Names()))
+	}
+	return values
+}
+
+// computedChanges returns the values computed from the old and new object of
+// an update as changes from the old to the new values.
+func (m *manager) computedChanges(from, to []interface{}) []output.Change {
+	names := m.scripts.Names()
+	if len(names) == 0 {
+		return nil
+	}
+	changes := make([]output.Change, 0, len(names))
+	for i, name := range names {
+		changes = append(changes, output.Change{Path: name, From: from[i], To: to[i], Op: output.OpComputed})
+	}
+	return changes
+}
+
+// computedSummary returns the computed values of an object as name=value
+// pairs.
+func (m *manager) computedSummary(obj client.Object) string {
+	values := m.computeValues(obj)
+	var summary []string
+	for i, name := range m.scripts.Names() {
+		if values[i] != nil {
+			summary = append(summary, fmt.Sprintf("%s=%v", name, values[i]))
+		}
+	}
+	return strings.Join(summary, " ")
+}
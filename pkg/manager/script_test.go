@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+func scriptDeployment(replicas, ready int64) client.Object {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":   "web",
+			"labels": map[string]interface{}{"app.kubernetes.io/name": "web"},
+		},
+		"spec":   map[string]interface{}{"replicas": replicas},
+		"status": map[string]interface{}{"readyReplicas": ready},
+	}}
+}
+
+func TestScriptFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		old    client.Object
+		new    client.Object
+		want   bool
+	}{
+		{filter: "", old: scriptDeployment(1, 1), new: scriptDeployment(2, 1), want: true},
+		{filter: "new.spec.replicas != old.spec.replicas", old: scriptDeployment(1, 1), new: scriptDeployment(2, 1), want: true},
+		{filter: "new.spec.replicas != old.spec.replicas", old: scriptDeployment(1, 0), new: scriptDeployment(1, 1), want: false},
+		{filter: `new.metadata.labels["app.kubernetes.io/name"] == "web"`, old: scriptDeployment(1, 0), new: scriptDeployment(1, 1), want: true},
+		{filter: `get(new, "status.conditions.0.type", "") == "Available"`, old: scriptDeployment(1, 0), new: scriptDeployment(1, 1), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			s, err := Config{Filter: tt.filter}.compileScripts()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.Filter(tt.old, tt.new)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Filter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScriptCompute(t *testing.T) {
+	s, err := Config{Compute: []string{
+		"ready=obj.status.readyReplicas / obj.spec.replicas",
+		"missing = obj.spec.replicas - get(obj, 'status.availableReplicas', 0)",
+		"name=obj.metadata.name",
+	}}.compileScripts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Names(), []string{"ready", "missing", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+	got, err := s.Compute(scriptDeployment(4, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{0.5, int64(4), "web"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Compute() = %#v, want %#v", got, want)
+	}
+}
+
+func TestCompileScriptsInvalid(t *testing.T) {
+	tests := []Config{
+		{Filter: "new.spec.replicas >"},
+		{Filter: "undefined(new)"},
+		{Compute: []string{"obj.spec.replicas"}},
+		{Compute: []string{"=obj.spec.replicas"}},
+		{Compute: []string{"a=obj.x", "a=obj.y"}},
+		{Compute: []string{"a=old.spec"}},
+	}
+	for _, c := range tests {
+		if _, err := c.compileScripts(); err == nil {
+			t.Errorf("compileScripts(%q, %q) succeeded, want an error", c.Filter, c.Compute)
+		}
+	}
+}
+
+// eventsWriter keeps the written events.
+type eventsWriter []output.Event
+
+func (w *eventsWriter) Write(e output.Event) error {
+	*w = append(*w, e)
+	return nil
+}
+
+// TestFilterUpdatesOnly checks the filter, an expression of old and new,
+// only drops updates, created and deleted objects are always reported.
+func TestFilterUpdatesOnly(t *testing.T) {
+	m, err := newManager(Config{Filter: "False"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w eventsWriter
+	m.outputs = &outputs{writer: &w}
+	ctx := context.Background()
+	now := time.Now()
+	m.OnCreate(ctx, now, scriptDeployment(1, 0), m.config)
+	m.OnUpdate(ctx, now, scriptDeployment(1, 0), scriptDeployment(1, 1), m.config)
+	m.OnDelete(ctx, now, scriptDeployment(1, 1), m.config)
+	var ops []string
+	for _, e := range w {
+		for _, c := range e.Changes {
+			ops = append(ops, c.Op)
+		}
+	}
+	if want := []string{output.OpCreated, output.OpDeleted}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("events = %v, want %v", ops, want)
+	}
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetSelectors parses the label and field selectors of the config. The
+// Labels map is merged into the label selector.
+func (c Config) GetSelectors() (labels.Selector, fields.Selector, error) {
+	ls, err := labels.Parse(c.LabelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid label selector %q: %v", c.LabelSelector, err)
+	}
+	if len(c.Labels) > 0 {
+		reqs, _ := labels.SelectorFromSet(c.Labels).Requirements()
+		ls = ls.Add(reqs...)
+	}
+	fs, err := fields.ParseSelector(c.FieldSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid field selector %q: %v", c.FieldSelector, err)
+	}
+	return ls, fs, nil
+}
+
+// serverFields are the fields every kind can be selected by on the server,
+// other fields are only supported by some kinds, e.g. status.phase of pods.
+var serverFields = map[string]struct{}{
+	"metadata.name":      {},
+	"metadata.namespace": {},
+}
+
+// serverFieldSelector returns the part of a field selector which is sent to
+// the server for every kind, the rest is matched by matchSelectors only.
+func serverFieldSelector(fs fields.Selector) fields.Selector {
+	if fs == nil {
+		return nil
+	}
+	var selectors []fields.Selector
+	for _, r := range fs.Requirements() {
+		if _, ok := serverFields[r.Field]; !ok {
+			continue
+		}
+		switch r.Operator {
+		case selection.Equals, selection.DoubleEquals:
+			selectors = append(selectors, fields.OneTermEqualSelector(r.Field, r.Value))
+		case selection.NotEquals:
+			selectors = append(selectors, fields.OneTermNotEqualSelector(r.Field, r.Value))
+		}
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+	return fields.AndSelectors(selectors...)
+}
+
+func matchSelectors(obj client.Object, ls labels.Selector, fs fields.Selector) bool {
+	if ls != nil && !ls.Empty() && !ls.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	if fs != nil && !fs.Empty() && !fs.Matches(objectFields(obj, fs)) {
+		return false
+	}
+	return true
+}
+
+// objectFields returns the values of all fields referenced by the selector,
+// e.g. metadata.name or status.phase.
+func objectFields(obj client.Object, fs fields.Selector) fields.Set {
+	set := fields.Set{
+		"metadata.name":      obj.GetName(),
+		"metadata.namespace": obj.GetNamespace(),
+	}
+	content, _ := objectContent(obj)
+	for _, r := range fs.Requirements() {
+		if _, ok := set[r.Field]; ok {
+			continue
+		}
+		v, found, err := unstructured.NestedFieldNoCopy(content, strings.Split(r.Field, ".")...)
+		if err != nil || !found || v == nil {
+			continue
+		}
+		set[r.Field] = fmt.Sprintf("%v", v)
+	}
+	return set
+}
@@ -0,0 +1,77 @@
+package manager
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMatchSelectors(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "podinfo",
+			"namespace": "default",
+			"labels": map[string]interface{}{
+				"app":  "podinfo",
+				"tier": "backend",
+			},
+		},
+		"status": map[string]interface{}{
+			"phase": "Running",
+		},
+	}}
+	tests := []struct {
+		name   string
+		config Config
+		want   bool
+	}{
+		{name: "empty", want: true},
+		{name: "equal", config: Config{LabelSelector: "app=podinfo"}, want: true},
+		{name: "in", config: Config{LabelSelector: "tier in (frontend,backend)"}, want: true},
+		{name: "notin", config: Config{LabelSelector: "tier notin (backend)"}, want: false},
+		{name: "not exists", config: Config{LabelSelector: "!canary"}, want: true},
+		{name: "labels map", config: Config{Labels: map[string]string{"app": "other"}}, want: false},
+		{name: "field name", config: Config{FieldSelector: "metadata.name=podinfo"}, want: true},
+		{name: "field status", config: Config{FieldSelector: "status.phase!=Running"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ls, fs, err := tt.config.GetSelectors()
+			if err != nil {
+				t.Fatalf("GetSelectors() error = %v", err)
+			}
+			if got := matchSelectors(obj, ls, fs); got != tt.want {
+				t.Errorf("matchSelectors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerFieldSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{selector: "", want: ""},
+		{selector: "status.phase=Running", want: ""},
+		{selector: "metadata.name=podinfo", want: "metadata.name=podinfo"},
+		{selector: "metadata.namespace!=kube-system,status.phase=Running", want: "metadata.namespace!=kube-system"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			_, fs, err := Config{FieldSelector: tt.selector}.GetSelectors()
+			if err != nil {
+				t.Fatalf("GetSelectors() error = %v", err)
+			}
+			var got string
+			if s := serverFieldSelector(fs); s != nil {
+				got = s.String()
+			}
+			if got != tt.want {
+				t.Errorf("serverFieldSelector() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
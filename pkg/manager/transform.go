@@ -0,0 +1,120 @@
+package manager
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// informerObject returns the object the informer of a kind is created for,
+// only the metadata of the kind in metadata-only mode.
+func (c Config) informerObject(o SchemeObject) client.Object {
+	if !c.MetadataOnly {
+		return o.Object
+	}
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(o.GVK())
+	return obj
+}
+
+// stripPaths returns the paths of the strip fields of the config for all
+// kinds or for the kind.
+func (c Config) stripPaths(o SchemeObject) [][]string {
+	var paths [][]string
+	for _, f := range splitList(c.StripFields) {
+		if i := strings.Index(f, ":"); i >= 0 {
+			if kind := f[:i]; !o.matches(kind) && !strings.EqualFold(kind, o.QualifiedName()) {
+				continue
+			}
+			f = f[i+1:]
+		}
+		paths = append(paths, strings.Split(f, "."))
+	}
+	return paths
+}
+
+// transform returns the transform of the informer of a kind. It strips the
+// managed fields if metadata is ignored and the fields of the kind, and
+// converts metadata-only objects to unstructured
+// ones of the kind, so they are diffed like full objects.
+func (c Config) transform(o SchemeObject) toolscache.TransformFunc {
+	gvk := o.GVK()
+	paths := c.stripPaths(o)
+	return func(obj interface{}) (interface{}, error) {
+		var u *unstructured.Unstructured
+		switch v := obj.(type) {
+		case *unstructured.Unstructured:
+			u = v
+		case *metav1.PartialObjectMetadata:
+			content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(v)
+			if err != nil {
+				return nil, err
+			}
+			u = &unstructured.Unstructured{Object: content}
+			u.SetGroupVersionKind(gvk)
+		case client.Object:
+			// typed objects of plugins are decoded without their kind
+			v.GetObjectKind().SetGroupVersionKind(gvk)
+			if c.IgnoreMetadata {
+				v.SetManagedFields(nil)
+			}
+			if len(paths) == 0 {
+				return v, nil
+			}
+			return stripTyped(v, paths)
+		default:
+			// e.g. DeletedFinalStateUnknown
+			return obj, nil
+		}
+		if c.IgnoreMetadata {
+			u.SetManagedFields(nil)
+		}
+		for _, p := range paths {
+			unstructured.RemoveNestedField(u.Object, p...)
+		}
+		return u, nil
+	}
+}
+
+func stripTyped(obj client.Object, paths [][]string) (client.Object, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		unstructured.RemoveNestedField(content, p...)
+	}
+	stripped := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, stripped); err != nil {
+		return nil, err
+	}
+	stripped.GetObjectKind().SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	return stripped, nil
+}
+
+// setTransform sets the transform of a kind on its informer before it is
+// started.
+func (c Config) setTransform(informer interface{}, o SchemeObject) error {
+	i, ok := informer.(interface {
+		SetTransform(toolscache.TransformFunc) error
+	})
+	if !ok {
+		return fmt.Errorf("informer of %s doesn't support transforms", o.QualifiedName())
+	}
+	return i.SetTransform(c.transform(o))
+}
+
+// checkMetadataOnly returns an error if the config needs more than the
+// metadata of the objects in metadata-only mode.
+func (c Config) checkMetadataOnly() error {
+	if c.MetadataOnly && c.scopeField() != "" {
+		return fmt.Errorf("scope %s needs the full objects, it can't be used with metadata only", c.Scope)
+	}
+	return nil
+}
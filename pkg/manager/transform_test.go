@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestTransform(t *testing.T) {
+	cm := testResource("v1", "ConfigMap", "configmaps", "cm")
+	config := Config{IgnoreMetadata: true, StripFields: "cm:data,deployments.apps:spec.template,metadata.annotations"}
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":          "config",
+			"managedFields": []interface{}{map[string]interface{}{"manager": "kubectl"}},
+			"annotations":   map[string]interface{}{"a": "b"},
+		},
+		"data": map[string]interface{}{"key": "value"},
+		"spec": map[string]interface{}{"template": "kept"},
+	}}
+	got, err := config.transform(cm)(obj)
+	if err != nil {
+		t.Fatalf("transform() error = %v", err)
+	}
+	u := got.(*unstructured.Unstructured)
+	for _, path := range [][]string{{"metadata", "managedFields"}, {"metadata", "annotations"}, {"data"}} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(u.Object, path...); found {
+			t.Errorf("transform() kept %v", path)
+		}
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "template"); !found {
+		t.Errorf("transform() removed spec.template of another kind")
+	}
+
+	meta := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Name:   "config",
+		Labels: map[string]string{"app": "web"},
+	}}
+	got, err = Config{MetadataOnly: true}.transform(cm)(meta)
+	if err != nil {
+		t.Fatalf("transform() of metadata error = %v", err)
+	}
+	u, ok := got.(*unstructured.Unstructured)
+	if !ok || u.GetKind() != "ConfigMap" || u.GetAPIVersion() != "v1" || u.GetLabels()["app"] != "web" {
+		t.Errorf("transform() of metadata = %#v", got)
+	}
+	deploy := testResource("apps/v1", "Deployment", "deployments", "deploy")
+	typed := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Name:          "web",
+		ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+	}}
+	typed.Spec.Template.Spec.Containers = []corev1.Container{{Name: "web"}}
+	got, err = Config{IgnoreMetadata: true, StripFields: "deploy:spec.template"}.transform(deploy)(typed)
+	if err != nil {
+		t.Fatalf("transform() of typed object error = %v", err)
+	}
+	d, ok := got.(*appsv1.Deployment)
+	if !ok || d.Kind != "Deployment" || d.ManagedFields != nil || len(d.Spec.Template.Spec.Containers) != 0 || d.Name != "web" {
+		t.Errorf("transform() of typed object = %#v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package manifests
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/nfyxhan/kubewatch/pkg/apis/v1alpha1"
+)
+
+// CustomResourceDefinition returns the CRD of KubeWatch resources.
+func CustomResourceDefinition() *apiextensionsv1.CustomResourceDefinition {
+	str := apiextensionsv1.JSONSchemaProps{Type: "string"}
+	boolean := apiextensionsv1.JSONSchemaProps{Type: "boolean"}
+	integer := apiextensionsv1.JSONSchemaProps{Type: "integer"}
+	list := apiextensionsv1.JSONSchemaProps{
+		Type:  "array",
+		Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &str},
+	}
+	webhook := apiextensionsv1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"url"},
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"name":   str,
+			"url":    str,
+			"method": str,
+			"headers": {
+				Type:                 "object",
+				AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{Allows: true, Schema: &str},
+			},
+			"paths":         list,
+			"template":      str,
+			"batchSize":     integer,
+			"batchInterval": str,
+			"maxRetries":    integer,
+			"queueSize":     integer,
+			"backoff":       str,
+			"timeout":       str,
+		},
+	}
+	spec := apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"groupVersion":      str,
+			"kinds":             list,
+			"excludeKinds":      list,
+			"namespaces":        list,
+			"excludeNamespaces": list,
+			"names":             list,
+			"excludeNames":      list,
+			"matchMode":         str,
+			"labelSelector":     str,
+			"fieldSelector":     str,
+			"pathPrefix":        str,
+			"pathTemplate":      str,
+			"ignoreMetadata":    boolean,
+			"enableAnnotations": boolean,
+			"diffFormat":        str,
+			"scope":             str,
+			"metadataOnly":      boolean,
+			"stripFields":       list,
+			"filter":            str,
+			"compute":           list,
+			"webhooks": {
+				Type:  "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &webhook},
+			},
+		},
+	}
+	status := apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"observedGeneration": integer,
+			"kinds":              list,
+			"conditions": {
+				Type: "array",
+				Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &apiextensionsv1.JSONSchemaProps{
+					Type:                   "object",
+					XPreserveUnknownFields: &[]bool{true}[0],
+				}},
+			},
+		},
+	}
+	return &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{APIVersion: apiextensionsv1.SchemeGroupVersion.String(), Kind: "CustomResourceDefinition"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "kubewatches." + v1alpha1.GroupName,
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: v1alpha1.GroupName,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:     "kubewatches",
+				Singular:   "kubewatch",
+				Kind:       "KubeWatch",
+				ListKind:   "KubeWatchList",
+				ShortNames: []string{"kw"},
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    v1alpha1.GroupVersion.Version,
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"apiVersion": str,
+							"kind":       str,
+							"metadata":   {Type: "object"},
+							"spec":       spec,
+							"status":     status,
+						},
+					},
+				},
+				Subresources: &apiextensionsv1.CustomResourceSubresources{
+					Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+				},
+				AdditionalPrinterColumns: []apiextensionsv1.CustomResourceColumnDefinition{
+					{Name: "Group-Version", Type: "string", JSONPath: ".spec.groupVersion"},
+					{Name: "Kinds", Type: "string", JSONPath: ".status.kinds"},
+					{Name: "Ready", Type: "string", JSONPath: `.status.conditions[?(@.type=="Ready")].status`},
+					{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+				},
+			}},
+		},
+	}
+}
@@ -0,0 +1,232 @@
+package manifests
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nfyxhan/kubewatch/pkg/apis/v1alpha1"
+)
+
+// Options of the manifests running kubewatch serve in a cluster.
+type Options struct {
+	Name      string
+	Namespace string
+	Image     string
+	// Args are passed to kubewatch serve.
+	Args []string
+	// Rules are the RBAC rules of the watched kinds.
+	Rules          []rbacv1.PolicyRule
+	MetricsAddress string
+	ProbeAddress   string
+	// KubeWatches adds the KubeWatch CRD and the rules to reconcile it.
+	KubeWatches bool
+}
+
+// dataDir is the writable directory of the Deployment.
+const dataDir = "/var/lib/kubewatch"
+
+// Objects returns the ServiceAccount, the ClusterRole and Role with their
+// bindings, and the Deployment running kubewatch serve, preceded by the
+// KubeWatch CRD if enabled.
+func Objects(opts Options) ([]runtime.Object, error) {
+	metricsPort, err := port(opts.MetricsAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics address %q: %v", opts.MetricsAddress, err)
+	}
+	probePort, err := port(opts.ProbeAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health probe address %q: %v", opts.ProbeAddress, err)
+	}
+	labels := map[string]string{
+		"app.kubernetes.io/name": opts.Name,
+	}
+	meta := metav1.ObjectMeta{
+		Name:      opts.Name,
+		Namespace: opts.Namespace,
+		Labels:    labels,
+	}
+	clusterMeta := metav1.ObjectMeta{
+		Name:   opts.Name,
+		Labels: labels,
+	}
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      opts.Name,
+		Namespace: opts.Namespace,
+	}}
+	serviceAccount := &corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: meta,
+	}
+	rules := append([]rbacv1.PolicyRule{}, opts.Rules...)
+	if opts.KubeWatches {
+		rules = append(rules,
+			rbacv1.PolicyRule{
+				APIGroups: []string{v1alpha1.GroupName},
+				Resources: []string{"kubewatches"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups: []string{v1alpha1.GroupName},
+				Resources: []string{"kubewatches/status"},
+				Verbs:     []string{"get", "update", "patch"},
+			},
+			// the kinds of a KubeWatch are checked against the ServiceAccount
+			// of its namespace
+			rbacv1.PolicyRule{
+				APIGroups: []string{"authorization.k8s.io"},
+				Resources: []string{"subjectaccessreviews"},
+				Verbs:     []string{"create"},
+			},
+		)
+	}
+	clusterRole := &rbacv1.ClusterRole{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRole"},
+		ObjectMeta: clusterMeta,
+		Rules:      rules,
+	}
+	clusterRoleBinding := &rbacv1.ClusterRoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "ClusterRoleBinding"},
+		ObjectMeta: clusterMeta,
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     opts.Name,
+		},
+		Subjects: subjects,
+	}
+	// leader election only needs access to its lease in its own namespace
+	role := &rbacv1.Role{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "Role"},
+		ObjectMeta: meta,
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{"coordination.k8s.io"},
+				Resources: []string{"leases"},
+				Verbs:     []string{"get", "create", "update"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"events"},
+				Verbs:     []string{"create", "patch"},
+			},
+		},
+	}
+	roleBinding := &rbacv1.RoleBinding{
+		TypeMeta:   metav1.TypeMeta{APIVersion: rbacv1.SchemeGroupVersion.String(), Kind: "RoleBinding"},
+		ObjectMeta: meta,
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     opts.Name,
+		},
+		Subjects: subjects,
+	}
+	probe := func(path string) *corev1.Probe {
+		return &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: path,
+					Port: intstr.FromString("probes"),
+				},
+			},
+		}
+	}
+	replicas := int32(1)
+	nonRoot := true
+	readOnlyRoot := true
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: appsv1.SchemeGroupVersion.String(), Kind: "Deployment"},
+		ObjectMeta: meta,
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: opts.Name,
+					// the root filesystem is read only, the discovery cache
+					// and relative --record paths are written to an emptyDir
+					Volumes: []corev1.Volume{{
+						Name:         "data",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}},
+					Containers: []corev1.Container{{
+						Name:       "kubewatch",
+						Image:      opts.Image,
+						Args:       append([]string{"serve", "--cache-dir=" + dataDir + "/cache"}, opts.Args...),
+						WorkingDir: dataDir,
+						VolumeMounts: []corev1.VolumeMount{{
+							Name:      "data",
+							MountPath: dataDir,
+						}},
+						Ports: []corev1.ContainerPort{
+							{Name: "metrics", ContainerPort: metricsPort},
+							{Name: "probes", ContainerPort: probePort},
+						},
+						LivenessProbe:  probe("/healthz"),
+						ReadinessProbe: probe("/readyz"),
+						SecurityContext: &corev1.SecurityContext{
+							RunAsNonRoot:           &nonRoot,
+							ReadOnlyRootFilesystem: &readOnlyRoot,
+						},
+					}},
+				},
+			},
+		},
+	}
+	objects := []runtime.Object{
+		serviceAccount,
+		clusterRole,
+		clusterRoleBinding,
+		role,
+		roleBinding,
+		deployment,
+	}
+	if opts.KubeWatches {
+		objects = append([]runtime.Object{CustomResourceDefinition()}, objects...)
+	}
+	return objects, nil
+}
+
+// Write writes the objects as YAML documents, without empty status and
+// creation timestamps.
+func Write(w io.Writer, objects []runtime.Object) error {
+	for _, obj := range objects {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		unstructured.RemoveNestedField(content, "status")
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(content, "spec", "template", "metadata", "creationTimestamp")
+		b, err := yaml.Marshal(content)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func port(address string) (int32, error) {
+	_, p, err := net.SplitHostPort(address)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(p, 10, 32)
+	return int32(i), err
+}
@@ -0,0 +1,71 @@
+package manifests
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestObjects(t *testing.T) {
+	opts := Options{
+		Name:      "kubewatch",
+		Namespace: "monitoring",
+		Image:     "kubewatch:test",
+		Args:      []string{"--kind=deployment"},
+		Rules: []rbacv1.PolicyRule{{
+			APIGroups: []string{"apps"},
+			Resources: []string{"deployments"},
+			Verbs:     []string{"get", "list", "watch"},
+		}},
+		MetricsAddress: ":6666",
+		ProbeAddress:   ":8081",
+	}
+	objects, err := Objects(opts)
+	if err != nil {
+		t.Fatalf("Objects() error = %v", err)
+	}
+	var kinds []string
+	for _, obj := range objects {
+		kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+	}
+	if got, want := strings.Join(kinds, ","), "ServiceAccount,ClusterRole,ClusterRoleBinding,Role,RoleBinding,Deployment"; got != want {
+		t.Errorf("kinds = %s, want %s", got, want)
+	}
+	d := objects[len(objects)-1].(*appsv1.Deployment)
+	c := d.Spec.Template.Spec.Containers[0]
+	if got, want := strings.Join(c.Args, " "), "serve --cache-dir=/var/lib/kubewatch/cache --kind=deployment"; got != want {
+		t.Errorf("args = %s, want %s", got, want)
+	}
+	if len(c.VolumeMounts) != 1 || c.VolumeMounts[0].MountPath != c.WorkingDir || d.Spec.Template.Spec.Volumes[0].EmptyDir == nil {
+		t.Errorf("writable directory = %v %q %v", c.VolumeMounts, c.WorkingDir, d.Spec.Template.Spec.Volumes)
+	}
+	if c.Ports[0].ContainerPort != 6666 || c.Ports[1].ContainerPort != 8081 {
+		t.Errorf("ports = %v", c.Ports)
+	}
+	bf := &bytes.Buffer{}
+	if err := Write(bf, objects); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if s := bf.String(); strings.Count(s, "---\n") != len(objects) || strings.Contains(s, "creationTimestamp") {
+		t.Errorf("Write() = %s", s)
+	}
+
+	opts.KubeWatches = true
+	if objects, err = Objects(opts); err != nil {
+		t.Fatalf("Objects() with KubeWatches error = %v", err)
+	}
+	if got := objects[0].GetObjectKind().GroupVersionKind().Kind; got != "CustomResourceDefinition" {
+		t.Errorf("first kind = %s, want CustomResourceDefinition", got)
+	}
+	if got := len(objects[2].(*rbacv1.ClusterRole).Rules); got != 4 {
+		t.Errorf("cluster role rules = %d, want 4", got)
+	}
+
+	opts.ProbeAddress = "8081"
+	if _, err := Objects(opts); err == nil {
+		t.Errorf("Objects() with invalid probe address, want error")
+	}
+}
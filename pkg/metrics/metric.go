@@ -11,7 +11,57 @@ var (
 			Name: "field_values",
 			Help: "field values",
 		},
-		[]string{"group", "version", "kind", "namespace", "name", "field"},
+		[]string{"cluster", "group", "version", "kind", "namespace", "name", "field"},
+	)
+	fieldInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "field_info",
+			Help: "current value of non numeric fields, always 1",
+		},
+		[]string{"cluster", "group", "version", "kind", "namespace", "name", "field", "value"},
+	)
+	events = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_total",
+			Help: "number of create, update and delete events",
+		},
+		[]string{"cluster", "group", "version", "kind", "namespace", "event"},
+	)
+	fieldChanges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "field_changes_total",
+			Help: "number of changes per field path",
+		},
+		[]string{"cluster", "group", "version", "kind", "namespace", "field"},
+	)
+	pluginValues = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "plugin_values",
+			Help: "values of objects extracted by plugins",
+		},
+		[]string{"cluster", "group", "version", "kind", "namespace", "name", "metric"},
+	)
+	computedValues = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "computed_values",
+			Help: "values computed from objects by the compute expressions",
+		},
+		[]string{"cluster", "group", "version", "kind", "namespace", "name", "compute"},
+	)
+	webhookDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_dropped_records_total",
+			Help: "number of records dropped because the queue of a webhook was full",
+		},
+		[]string{"webhook"},
+	)
+	changeInterval = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "object_change_interval_seconds",
+			Help:    "time between successive changes of an object",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+		},
+		[]string{"cluster", "group", "version", "kind", "namespace"},
 	)
 )
 
@@ -19,6 +69,43 @@ func GetMetricsFieldValues() *prometheus.GaugeVec {
 	return fieldValue
 }
 
+func GetMetricsFieldInfo() *prometheus.GaugeVec {
+	return fieldInfo
+}
+
+func GetMetricsPluginValues() *prometheus.GaugeVec {
+	return pluginValues
+}
+
+func GetMetricsComputedValues() *prometheus.GaugeVec {
+	return computedValues
+}
+
+func GetMetricsWebhookDropped() *prometheus.CounterVec {
+	return webhookDropped
+}
+
+func GetMetricsEvents() *prometheus.CounterVec {
+	return events
+}
+
+func GetMetricsFieldChanges() *prometheus.CounterVec {
+	return fieldChanges
+}
+
+func GetMetricsChangeInterval() *prometheus.HistogramVec {
+	return changeInterval
+}
+
 func init() {
-	metrics.Registry.MustRegister(fieldValue)
+	metrics.Registry.MustRegister(
+		fieldValue,
+		fieldInfo,
+		pluginValues,
+		computedValues,
+		webhookDropped,
+		events,
+		fieldChanges,
+		changeInterval,
+	)
 }
@@ -0,0 +1,373 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+
+	ParserNumber    = "number"
+	ParserBool      = "bool"
+	ParserQuantity  = "quantity"
+	ParserDuration  = "duration"
+	ParserTimestamp = "timestamp"
+	ParserEnum      = "enum"
+
+	AggregateSum = "sum"
+	AggregateMax = "max"
+	AggregateMin = "min"
+)
+
+// Rule maps a JSONPath expression of a kind to a metric.
+type Rule struct {
+	// Name of the metric.
+	Name string `json:"name"`
+	Help string `json:"help,omitempty"`
+	// Type is gauge (default) or counter. A counter is increased by the
+	// increase of the value, e.g. of restartCount.
+	Type string `json:"type,omitempty"`
+	// Group and Kind select the objects, all groups if Group is empty.
+	Group string `json:"group,omitempty"`
+	Kind  string `json:"kind"`
+	// Path is a JSONPath expression like {.status.readyReplicas}.
+	Path string `json:"path"`
+	// Parser converts the value, one of number (default), bool, quantity,
+	// duration, timestamp or enum.
+	Parser string `json:"parser,omitempty"`
+	// Enum maps values to numbers for the enum parser.
+	Enum map[string]float64 `json:"enum,omitempty"`
+	// Aggregate combines multiple values of the path, one of sum (default),
+	// max or min.
+	Aggregate string `json:"aggregate,omitempty"`
+	// Labels maps metric labels to object label keys.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type RulesFile struct {
+	Rules []Rule `json:"rules"`
+}
+
+type rule struct {
+	Rule
+	path      *jsonpath.JSONPath
+	labelKeys []string
+	gauge     *prometheus.GaugeVec
+	counter   *prometheus.CounterVec
+}
+
+// RuleSet evaluates metric rules against objects.
+type RuleSet struct {
+	sync.Mutex
+	rules []*rule
+	// last values of counters, to add their increase
+	last map[string]float64
+}
+
+// LoadRules reads a rules file and registers its metrics.
+func LoadRules(path string) (*RuleSet, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := RulesFile{}
+	if err := yaml.UnmarshalStrict(b, &f); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %v", path, err)
+	}
+	return NewRuleSet(f.Rules)
+}
+
+func NewRuleSet(rules []Rule) (*RuleSet, error) {
+	rs := &RuleSet{
+		last: make(map[string]float64),
+	}
+	collectors := make(map[string]*rule)
+	for _, r := range rules {
+		cr, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+		if c, ok := collectors[cr.Name]; ok {
+			if c.Type != cr.Type || strings.Join(c.labelKeys, ",") != strings.Join(cr.labelKeys, ",") {
+				return nil, fmt.Errorf("rule %s: type and labels must be the same for all rules of a metric", r.Name)
+			}
+			cr.gauge, cr.counter = c.gauge, c.counter
+		} else if err := cr.register(); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+		collectors[cr.Name] = cr
+		rs.rules = append(rs.rules, cr)
+	}
+	return rs, nil
+}
+
+func compileRule(r Rule) (*rule, error) {
+	if r.Name == "" || r.Kind == "" || r.Path == "" {
+		return nil, fmt.Errorf("name, kind and path are required")
+	}
+	if r.Type == "" {
+		r.Type = TypeGauge
+	}
+	if r.Parser == "" {
+		r.Parser = ParserNumber
+	}
+	if r.Aggregate == "" {
+		r.Aggregate = AggregateSum
+	}
+	if r.Help == "" {
+		r.Help = fmt.Sprintf("%s of %s", r.Path, r.Kind)
+	}
+	switch r.Type {
+	case TypeGauge, TypeCounter:
+	default:
+		return nil, fmt.Errorf("unknown type %s", r.Type)
+	}
+	switch r.Parser {
+	case ParserNumber, ParserBool, ParserQuantity, ParserDuration, ParserTimestamp:
+	case ParserEnum:
+		if len(r.Enum) == 0 {
+			return nil, fmt.Errorf("enum parser without enum values")
+		}
+	default:
+		return nil, fmt.Errorf("unknown parser %s", r.Parser)
+	}
+	switch r.Aggregate {
+	case AggregateSum, AggregateMax, AggregateMin:
+	default:
+		return nil, fmt.Errorf("unknown aggregate %s", r.Aggregate)
+	}
+	p := jsonpath.New(r.Name).AllowMissingKeys(true)
+	if err := p.Parse(r.Path); err != nil {
+		return nil, fmt.Errorf("invalid path %s: %v", r.Path, err)
+	}
+	keys := []string{"cluster", "namespace", "name"}
+	extra := make([]string, 0, len(r.Labels))
+	for k := range r.Labels {
+		extra = append(extra, k)
+	}
+	sort.Strings(extra)
+	return &rule{
+		Rule:      r,
+		path:      p,
+		labelKeys: append(keys, extra...),
+	}, nil
+}
+
+// register registers the metric of the rule, reusing a metric registered by
+// an earlier rule set with the same name.
+func (r *rule) register() error {
+	var c prometheus.Collector
+	switch r.Type {
+	case TypeCounter:
+		r.counter = prometheus.NewCounterVec(prometheus.CounterOpts{Name: r.Name, Help: r.Help}, r.labelKeys)
+		c = r.counter
+	default:
+		r.gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: r.Name, Help: r.Help}, r.labelKeys)
+		c = r.gauge
+	}
+	err := metrics.Registry.Register(c)
+	are, ok := err.(prometheus.AlreadyRegisteredError)
+	if !ok {
+		return err
+	}
+	switch existing := are.ExistingCollector.(type) {
+	case *prometheus.CounterVec:
+		if r.counter != nil {
+			r.counter = existing
+			return nil
+		}
+	case *prometheus.GaugeVec:
+		if r.gauge != nil {
+			r.gauge = existing
+			return nil
+		}
+	}
+	return err
+}
+
+func (r *rule) matches(obj client.Object) bool {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if r.Group != "" && r.Group != gvk.Group {
+		return false
+	}
+	return strings.EqualFold(r.Kind, gvk.Kind)
+}
+
+func (r *rule) labelValues(cluster string, obj client.Object) []string {
+	values := []string{cluster, obj.GetNamespace(), obj.GetName()}
+	labels := obj.GetLabels()
+	for _, k := range r.labelKeys[3:] {
+		values = append(values, labels[r.Labels[k]])
+	}
+	return values
+}
+
+// Observe updates the metrics of all rules matching the object of a cluster.
+func (rs *RuleSet) Observe(cluster string, obj client.Object) error {
+	if rs == nil || len(rs.rules) == 0 {
+		return nil
+	}
+	content, err := objectContent(obj)
+	if err != nil {
+		return err
+	}
+	var errs []string
+	for _, r := range rs.rules {
+		if !r.matches(obj) {
+			continue
+		}
+		if err := rs.observe(r, r.labelValues(cluster, obj), content); err != nil {
+			errs = append(errs, fmt.Sprintf("rule %s: %v", r.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (rs *RuleSet) observe(r *rule, labels []string, content map[string]interface{}) error {
+	results, err := r.path.FindResults(content)
+	if err != nil {
+		return err
+	}
+	var values []float64
+	for _, result := range results {
+		for _, v := range result {
+			if !v.CanInterface() {
+				continue
+			}
+			f, err := ParseValue(r.Parser, v.Interface(), r.Enum)
+			if err != nil {
+				return err
+			}
+			values = append(values, f)
+		}
+	}
+	if len(values) == 0 {
+		rs.delete(r, labels)
+		return nil
+	}
+	value := values[0]
+	for _, v := range values[1:] {
+		switch r.Aggregate {
+		case AggregateMax:
+			if v > value {
+				value = v
+			}
+		case AggregateMin:
+			if v < value {
+				value = v
+			}
+		default:
+			value += v
+		}
+	}
+	if r.gauge != nil {
+		r.gauge.WithLabelValues(labels...).Set(value)
+		return nil
+	}
+	key := r.Name + "/" + strings.Join(labels, "/")
+	rs.Lock()
+	last, ok := rs.last[key]
+	rs.last[key] = value
+	rs.Unlock()
+	c := r.counter.WithLabelValues(labels...)
+	switch {
+	case !ok:
+		c.Add(0)
+	case value > last:
+		c.Add(value - last)
+	case value < last:
+		// the source was reset
+		c.Add(value)
+	}
+	return nil
+}
+
+// Delete removes all metrics of the object of a cluster.
+func (rs *RuleSet) Delete(cluster string, obj client.Object) {
+	if rs == nil {
+		return
+	}
+	for _, r := range rs.rules {
+		if r.matches(obj) {
+			rs.delete(r, r.labelValues(cluster, obj))
+		}
+	}
+}
+
+func (rs *RuleSet) delete(r *rule, labels []string) {
+	if r.gauge != nil {
+		r.gauge.DeleteLabelValues(labels...)
+		return
+	}
+	rs.Lock()
+	delete(rs.last, r.Name+"/"+strings.Join(labels, "/"))
+	rs.Unlock()
+	r.counter.DeleteLabelValues(labels...)
+}
+
+// ParseValue converts a field value to a metric value.
+func ParseValue(parser string, v interface{}, enum map[string]float64) (float64, error) {
+	s := fmt.Sprint(v)
+	switch parser {
+	case ParserBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return 0, err
+		}
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	case ParserQuantity:
+		q, err := resource.ParseQuantity(s)
+		if err != nil {
+			return 0, err
+		}
+		return q.AsApproximateFloat64(), nil
+	case ParserDuration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+		return d.Seconds(), nil
+	case ParserTimestamp:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return 0, err
+		}
+		return float64(t.UnixNano()) / float64(time.Second), nil
+	case ParserEnum:
+		f, ok := enum[s]
+		if !ok {
+			return 0, fmt.Errorf("value %s not in enum", s)
+		}
+		return f, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func objectContent(obj client.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.Object, nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestParseValue(t *testing.T) {
+	enum := map[string]float64{"Running": 1}
+	tests := []struct {
+		parser  string
+		value   interface{}
+		want    float64
+		wantErr bool
+	}{
+		{ParserNumber, int64(3), 3, false},
+		{ParserNumber, "1.5", 1.5, false},
+		{ParserNumber, "500m", 0, true},
+		{ParserBool, true, 1, false},
+		{ParserQuantity, "500m", 0.5, false},
+		{ParserQuantity, "1Gi", 1 << 30, false},
+		{ParserDuration, "1m30s", 90, false},
+		{ParserTimestamp, "1970-01-01T00:01:00Z", 60, false},
+		{ParserEnum, "Running", 1, false},
+		{ParserEnum, "Failed", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseValue(tt.parser, tt.value, enum)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseValue(%s, %v) error = %v, wantErr %v", tt.parser, tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseValue(%s, %v) = %v, want %v", tt.parser, tt.value, got, tt.want)
+		}
+	}
+}
+
+func rulesPod(restarts ...int64) *unstructured.Unstructured {
+	statuses := make([]interface{}, 0, len(restarts))
+	for _, r := range restarts {
+		statuses = append(statuses, map[string]interface{}{"restartCount": r})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "web-0",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app.kubernetes.io/name": "web"},
+		},
+		"status": map[string]interface{}{
+			"phase":             "Running",
+			"containerStatuses": statuses,
+		},
+	}}
+}
+
+func TestRuleSetObserve(t *testing.T) {
+	restarts := "{.status.containerStatuses[*].restartCount}"
+	rs, err := NewRuleSet([]Rule{
+		{Name: "test_pod_restarts_sum", Kind: "Pod", Path: restarts, Labels: map[string]string{"app": "app.kubernetes.io/name"}},
+		{Name: "test_pod_restarts_max", Kind: "pod", Path: restarts, Aggregate: AggregateMax},
+		{Name: "test_pod_restarts_min", Kind: "Pod", Path: restarts, Aggregate: AggregateMin},
+		{Name: "test_pod_restarts_total", Kind: "Pod", Path: restarts, Type: TypeCounter},
+		{Name: "test_pod_running", Kind: "Pod", Path: "{.status.phase}", Parser: ParserEnum, Enum: map[string]float64{"Running": 1}},
+		{Name: "test_deployment_replicas", Kind: "Deployment", Group: "apps", Path: "{.spec.replicas}"},
+	})
+	if err != nil {
+		t.Fatalf("NewRuleSet() error = %v", err)
+	}
+	labels := []string{"staging", "default", "web-0"}
+	value := func(i int) float64 {
+		r := rs.rules[i]
+		if r.counter != nil {
+			return testutil.ToFloat64(r.counter.WithLabelValues(labels...))
+		}
+		l := labels
+		if len(r.labelKeys) > 3 {
+			l = append(l, "web")
+		}
+		return testutil.ToFloat64(r.gauge.WithLabelValues(l...))
+	}
+	steps := []struct {
+		restarts []int64
+		want     []float64
+	}{
+		// sum, max, min, counter, enum
+		{restarts: []int64{1, 3}, want: []float64{4, 3, 1, 0, 1}},
+		{restarts: []int64{2, 5}, want: []float64{7, 5, 2, 3, 1}},
+		// the containers restarted, the counter adds the new value
+		{restarts: []int64{0, 1}, want: []float64{1, 1, 0, 4, 1}},
+	}
+	for i, s := range steps {
+		if err := rs.Observe("staging", rulesPod(s.restarts...)); err != nil {
+			t.Fatalf("step %d: Observe() error = %v", i, err)
+		}
+		for j, want := range s.want {
+			if got := value(j); got != want {
+				t.Errorf("step %d: %s = %v, want %v", i, rs.rules[j].Name, got, want)
+			}
+		}
+	}
+	if n := testutil.CollectAndCount(rs.rules[5].gauge); n != 0 {
+		t.Errorf("%s has %d series for a pod, want 0", rs.rules[5].Name, n)
+	}
+
+	rs.Delete("staging", rulesPod())
+	for _, r := range rs.rules[:5] {
+		var n int
+		if r.counter != nil {
+			n = testutil.CollectAndCount(r.counter)
+		} else {
+			n = testutil.CollectAndCount(r.gauge)
+		}
+		if n != 0 {
+			t.Errorf("%s has %d series after Delete(), want 0", r.Name, n)
+		}
+	}
+	if len(rs.last) != 0 {
+		t.Errorf("last counter values after Delete() = %v", rs.last)
+	}
+}
@@ -0,0 +1,141 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/nfyxhan/kubewatch/pkg/utils"
+)
+
+// UnifiedDiff returns the unified diff of two texts, colourised if color is
+// set. It returns an empty string if the texts are equal.
+func UnifiedDiff(from, to, fromName, toName string, color bool) (string, error) {
+	s, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(from),
+		B:        splitLines(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil || !color {
+		return s, err
+	}
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+			lines[i] = colorLine(utils.Magenta, l)
+		case strings.HasPrefix(l, "@@"):
+			lines[i] = colorLine(utils.Cyan, l)
+		case strings.HasPrefix(l, "+"):
+			lines[i] = colorLine(utils.Green, l)
+		case strings.HasPrefix(l, "-"):
+			lines[i] = colorLine(utils.Red, l)
+		}
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// splitLines splits a text after its newlines, unlike difflib.SplitLines it
+// doesn't add an empty last line to texts ending with a newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// SideBySide renders two texts next to each other within width columns.
+// Changed lines are marked with '|', removed ones with '<' and added ones
+// with '>'. It returns an empty string if the texts are equal.
+func SideBySide(from, to string, width int, color bool) string {
+	return sideBySide(from, to, width, 3, color)
+}
+
+// sideBySide is SideBySide with context lines around the changes, the
+// whole texts are rendered with a context of their length.
+func sideBySide(from, to string, width, context int, color bool) string {
+	a := strings.Split(strings.TrimSuffix(from, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(to, "\n"), "\n")
+	col := (width - 3) / 2
+	if col < 10 {
+		col = 10
+	}
+	bf := &strings.Builder{}
+	line := func(l, mark, r string) {
+		l, r = fit(l, col), fit(r, col)
+		switch {
+		case !color:
+		case mark == "|":
+			l, r = utils.ColorString(utils.Red, "%s", l), utils.ColorString(utils.Green, "%s", r)
+		case mark == "<":
+			l = utils.ColorString(utils.Red, "%s", l)
+		case mark == ">":
+			r = utils.ColorString(utils.Green, "%s", r)
+		}
+		fmt.Fprintf(bf, "%s %s %s\n", l, mark, strings.TrimRight(r, " "))
+	}
+	var changed bool
+	for i, group := range difflib.NewMatcher(a, b).GetGroupedOpCodes(context) {
+		if i > 0 {
+			line(strings.Repeat("-", col), "+", strings.Repeat("-", col))
+		}
+		for _, c := range group {
+			switch c.Tag {
+			case 'e':
+				for i := c.I1; i < c.I2; i++ {
+					line(a[i], " ", b[c.J1+i-c.I1])
+				}
+			case 'r':
+				changed = true
+				for i := 0; i < c.I2-c.I1 || i < c.J2-c.J1; i++ {
+					var l, r string
+					mark := "|"
+					if i < c.I2-c.I1 {
+						l = a[c.I1+i]
+					} else {
+						mark = ">"
+					}
+					if i < c.J2-c.J1 {
+						r = b[c.J1+i]
+					} else {
+						mark = "<"
+					}
+					line(l, mark, r)
+				}
+			case 'd':
+				changed = true
+				for i := c.I1; i < c.I2; i++ {
+					line(a[i], "<", "")
+				}
+			case 'i':
+				changed = true
+				for j := c.J1; j < c.J2; j++ {
+					line("", ">", b[j])
+				}
+			}
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return bf.String()
+}
+
+func fit(s string, width int) string {
+	r := []rune(s)
+	if len(r) > width {
+		return string(r[:width-1]) + "~"
+	}
+	return s + strings.Repeat(" ", width-len(r))
+}
+
+func colorLine(color, l string) string {
+	if strings.HasSuffix(l, "\n") {
+		return utils.ColorString(color, "%s", strings.TrimSuffix(l, "\n")) + "\n"
+	}
+	return utils.ColorString(color, "%s", l)
+}
@@ -0,0 +1,96 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nfyxhan/kubewatch/pkg/utils"
+)
+
+const (
+	diffFrom = "spec:\n  replicas: 1\nstatus:\n  phase: Pending\n"
+	diffTo   = "spec:\n  replicas: 1\nstatus:\n  phase: Running\n  ready: true\n"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		color bool
+		want  string
+	}{
+		{name: "equal", from: diffFrom, to: diffFrom, want: ""},
+		{
+			name: "plain",
+			from: diffFrom,
+			to:   diffTo,
+			want: "--- a/po\n+++ b/po\n@@ -1,4 +1,5 @@\n spec:\n   replicas: 1\n status:\n-  phase: Pending\n+  phase: Running\n+  ready: true\n",
+		},
+		{
+			name:  "color",
+			from:  diffFrom,
+			to:    diffTo,
+			color: true,
+			want: utils.ColorString(utils.Magenta, "%s", "--- a/po") + "\n" +
+				utils.ColorString(utils.Magenta, "%s", "+++ b/po") + "\n" +
+				utils.ColorString(utils.Cyan, "%s", "@@ -1,4 +1,5 @@") + "\n" +
+				" spec:\n   replicas: 1\n status:\n" +
+				utils.ColorString(utils.Red, "%s", "-  phase: Pending") + "\n" +
+				utils.ColorString(utils.Green, "%s", "+  phase: Running") + "\n" +
+				utils.ColorString(utils.Green, "%s", "+  ready: true") + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnifiedDiff(tt.from, tt.to, "a/po", "b/po", tt.color)
+			if err != nil {
+				t.Fatalf("UnifiedDiff() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	// columns of (33-3)/2 = 15
+	row := func(l, mark, r string) string {
+		return l + strings.Repeat(" ", 15-len(l)) + " " + mark + " " + r + "\n"
+	}
+	tests := []struct {
+		name  string
+		from  string
+		to    string
+		color bool
+		want  string
+	}{
+		{name: "equal", from: diffFrom, to: diffFrom, want: ""},
+		{
+			name: "plain",
+			from: diffFrom,
+			to:   diffTo,
+			want: row("spec:", " ", "spec:") +
+				row("  replicas: 1", " ", "  replicas: 1") +
+				row("status:", " ", "status:") +
+				row("  phase: Pendi~", "|", "  phase: Runni~") +
+				row("", ">", "  ready: true"),
+		},
+		{
+			name:  "color",
+			from:  "a: 1\n",
+			to:    "a: 2\nb: 1\n",
+			color: true,
+			want: utils.ColorString(utils.Red, "%s", "a: 1           ") + " | " + utils.ColorString(utils.Green, "%s", "a: 2           ") + "\n" +
+				"                > " + utils.ColorString(utils.Green, "%s", "b: 1           ") + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SideBySide(tt.from, tt.to, 33, tt.color); got != tt.want {
+				t.Errorf("SideBySide() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+type jsonWriter struct {
+	sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONWriter writes one JSON document per change. With indent set the
+// documents are pretty printed, otherwise every record is a single line
+// (NDJSON).
+func NewJSONWriter(w io.Writer, indent bool) Writer {
+	enc := json.NewEncoder(w)
+	if indent {
+		enc.SetIndent("", "  ")
+	}
+	return &jsonWriter{
+		enc: enc,
+	}
+}
+
+func (j *jsonWriter) Write(e Event) error {
+	j.Lock()
+	defer j.Unlock()
+	for _, r := range e.Records() {
+		if err := j.enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type yamlWriter struct {
+	sync.Mutex
+	w io.Writer
+}
+
+// NewYAMLWriter writes one YAML document per change.
+func NewYAMLWriter(w io.Writer) Writer {
+	return &yamlWriter{
+		w: w,
+	}
+}
+
+func (y *yamlWriter) Write(e Event) error {
+	y.Lock()
+	defer y.Unlock()
+	for _, r := range e.Records() {
+		b, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(y.w, "---\n%s", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	FormatTable  = "table"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatYAML   = "yaml"
+	FormatTUI    = "tui"
+)
+
+// Ops of whole objects, the changes of fields have the ops create, update and
+// delete.
+const (
+	OpCreated = "created"
+	OpDeleted = "deleted"
+	// OpComputed are the values computed from the object, not a field.
+	OpComputed = "computed"
+)
+
+var Formats = []string{
+	FormatTable,
+	FormatJSON,
+	FormatNDJSON,
+	FormatYAML,
+	FormatTUI,
+}
+
+// Change is a single changed field of an object.
+type Change struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+	Op   string      `json:"op"`
+}
+
+// Event groups all changes observed on one object at one time.
+type Event struct {
+	Time time.Time
+	// Cluster is the kubeconfig context of the object when watching
+	// multiple clusters.
+	Cluster string
+	// Watch is the namespace/name of the KubeWatch resource the object is
+	// watched by, empty for watches of the command line.
+	Watch     string
+	Group     string
+	Version   string
+	Kind      string
+	Namespace string
+	Name      string
+	Changes   []Change
+	// Diff is the rendered text diff of the object, set instead of
+	// Changes by the unified and side-by-side diff formats.
+	Diff string
+	// Old and New are the full objects, shown by the interactive output.
+	Old interface{}
+	New interface{}
+}
+
+// Record is the machine readable form of a single change.
+type Record struct {
+	Time      time.Time   `json:"time"`
+	Cluster   string      `json:"cluster,omitempty"`
+	Watch     string      `json:"watch,omitempty"`
+	Group     string      `json:"group"`
+	Version   string      `json:"version"`
+	Kind      string      `json:"kind"`
+	Namespace string      `json:"namespace,omitempty"`
+	Name      string      `json:"name"`
+	Path      string      `json:"path"`
+	From      interface{} `json:"from"`
+	To        interface{} `json:"to"`
+	Op        string      `json:"op"`
+	Diff      string      `json:"diff,omitempty"`
+}
+
+// ObjectOp returns OpCreated or OpDeleted for events of whole objects, and
+// an empty string for changes of fields.
+func (e Event) ObjectOp() string {
+	if len(e.Changes) != 1 {
+		return ""
+	}
+	switch op := e.Changes[0].Op; op {
+	case OpCreated, OpDeleted:
+		return op
+	}
+	return ""
+}
+
+// Key returns the kind and name of the object of the event, prefixed by its
+// cluster if set.
+func (e Event) Key(namespace bool) string {
+	key := e.Kind + "/" + e.Name
+	if namespace && e.Namespace != "" {
+		key = e.Kind + "/" + e.Namespace + "/" + e.Name
+	}
+	if e.Cluster != "" {
+		key = e.Cluster + ":" + key
+	}
+	return key
+}
+
+func (e Event) Records() []Record {
+	if e.Diff != "" {
+		return []Record{{
+			Time:      e.Time,
+			Cluster:   e.Cluster,
+			Watch:     e.Watch,
+			Group:     e.Group,
+			Version:   e.Version,
+			Kind:      e.Kind,
+			Namespace: e.Namespace,
+			Name:      e.Name,
+			Op:        "update",
+			Diff:      e.Diff,
+		}}
+	}
+	records := make([]Record, 0, len(e.Changes))
+	for _, c := range e.Changes {
+		records = append(records, Record{
+			Time:      e.Time,
+			Cluster:   e.Cluster,
+			Watch:     e.Watch,
+			Group:     e.Group,
+			Version:   e.Version,
+			Kind:      e.Kind,
+			Namespace: e.Namespace,
+			Name:      e.Name,
+			Path:      c.Path,
+			From:      c.From,
+			To:        c.To,
+			Op:        c.Op,
+		})
+	}
+	return records
+}
+
+// Writer is a sink for change events.
+type Writer interface {
+	Write(e Event) error
+}
+
+type Options struct {
+	ColumnWidthMax int
+	RowWidthMax    int
+	MaxRows        int
+}
+
+func NewWriter(format string, w io.Writer, opts Options) (Writer, error) {
+	switch strings.ToLower(format) {
+	case "", FormatTable:
+		return NewTableWriter(w, opts), nil
+	case FormatJSON:
+		return NewJSONWriter(w, true), nil
+	case FormatNDJSON:
+		return NewJSONWriter(w, false), nil
+	case FormatYAML:
+		return NewYAMLWriter(w), nil
+	case FormatTUI:
+		return NewTUIWriter(os.Stdin, w, opts)
+	}
+	return nil, fmt.Errorf("unknown output format %q, must be one of %s", format, strings.Join(Formats, "|"))
+}
+
+// MultiWriter writes every event to all of the given writers.
+func MultiWriter(writers ...Writer) Writer {
+	return multiWriter(writers)
+}
+
+type multiWriter []Writer
+
+func (m multiWriter) Write(e Event) error {
+	var errs []string
+	for _, w := range m {
+		if err := w.Write(e); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestNewWriter(t *testing.T) {
+	e := Event{
+		Time:      time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		Group:     "apps",
+		Version:   "v1",
+		Kind:      "Deployment",
+		Namespace: "default",
+		Name:      "podinfo",
+		Changes: []Change{
+			{Path: "status/readyReplicas", From: 1, To: 2, Op: "update"},
+			{Path: "status/replicas", From: nil, To: 2, Op: "create"},
+		},
+	}
+	type args struct {
+		format string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "ndjson",
+			args: args{format: FormatNDJSON},
+			want: `{"time":"2022-01-02T03:04:05Z","group":"apps","version":"v1","kind":"Deployment","namespace":"default","name":"podinfo","path":"status/readyReplicas","from":1,"to":2,"op":"update"}
+{"time":"2022-01-02T03:04:05Z","group":"apps","version":"v1","kind":"Deployment","namespace":"default","name":"podinfo","path":"status/replicas","from":null,"to":2,"op":"create"}
+`,
+		},
+		{
+			name: "yaml",
+			args: args{format: FormatYAML},
+			want: `---
+from: 1
+group: apps
+kind: Deployment
+name: podinfo
+namespace: default
+op: update
+path: status/readyReplicas
+time: "2022-01-02T03:04:05Z"
+to: 2
+version: v1
+---
+from: null
+group: apps
+kind: Deployment
+name: podinfo
+namespace: default
+op: create
+path: status/replicas
+time: "2022-01-02T03:04:05Z"
+to: 2
+version: v1
+`,
+		},
+		{
+			name:    "unknown",
+			args:    args{format: "xml"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bf := bytes.NewBuffer(nil)
+			w, err := NewWriter(tt.args.format, bf, Options{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewWriter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := w.Write(e); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got := bf.String(); got != tt.want {
+				t.Errorf("Write() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jedib0t/go-pretty/table"
+
+	"github.com/nfyxhan/kubewatch/pkg/utils"
+)
+
+type tableWriter struct {
+	sync.Mutex
+	w    io.Writer
+	opts Options
+	rows []table.Row
+}
+
+// NewTableWriter renders the latest events as a full screen table,
+// redrawing the terminal on every event.
+func NewTableWriter(w io.Writer, opts Options) Writer {
+	return &tableWriter{
+		w:    w,
+		opts: opts,
+	}
+}
+
+func (t *tableWriter) Write(e Event) error {
+	if len(e.Changes) == 0 && e.Diff == "" {
+		return nil
+	}
+	t.Lock()
+	defer t.Unlock()
+	now := e.Time.Local().Format("15:04:05.999")
+	key := e.Key(false)
+	if e.Diff != "" {
+		// text diffs don't fit into table cells, they are streamed instead
+		_, err := fmt.Fprintf(t.w, "%s %s\n%s\n", utils.ColorString(utils.Blue, now), utils.ColorString(utils.Blue, key), e.Diff)
+		return err
+	}
+	var rows []table.Row
+	if op := e.ObjectOp(); op != "" {
+		rows = objectRows(now, key, op, e.Changes[0])
+	} else {
+		rows = changeRows(now, key, e.Changes)
+	}
+	maxRows := t.opts.MaxRows
+	if len(rows) > maxRows {
+		maxRows = len(rows)
+	}
+	t.rows = append(t.rows, rows...)
+	if len(t.rows) > maxRows {
+		t.rows = t.rows[len(t.rows)-maxRows:]
+	}
+	tw := NewTable(t.opts)
+	tw.AppendRows(t.rows)
+	s := tw.Render()
+	_, err := fmt.Fprintf(t.w, "\033c%s", s)
+	return err
+}
+
+func changeRows(now, key string, changes []Change) []table.Row {
+	rows := []table.Row{{
+		utils.ColorString(utils.Blue, now),
+		utils.ColorString(utils.Blue, key),
+		"",
+		"",
+		"",
+	}}
+	for _, c := range changes {
+		from := c.From
+		if from == nil {
+			from = "<nil>"
+		}
+		to := c.To
+		if to == nil {
+			to = "<nil>"
+		}
+		rows = append(rows, table.Row{
+			"",
+			c.Path,
+			from,
+			to,
+			c.Op,
+		})
+	}
+	return rows
+}
+
+// objectRows renders a created or deleted object as a single row, green or
+// red, with its summary and deletion reason.
+func objectRows(now, key, op string, c Change) []table.Row {
+	color := utils.Green
+	if op == OpDeleted {
+		color = utils.Red
+	}
+	return []table.Row{{
+		utils.ColorString(utils.Blue, now),
+		utils.ColorString(color, key),
+		fmt.Sprint(emptyNil(c.From)),
+		fmt.Sprint(emptyNil(c.To)),
+		utils.ColorString(color, op),
+	}}
+}
+
+func emptyNil(v interface{}) interface{} {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+func NewTable(opts Options) table.Writer {
+	t := table.NewWriter()
+	columnConfigs := make([]table.ColumnConfig, 0)
+	header := table.Row{"time", "key", "from", "to", "op"}
+	for i := 0; i < len(header); i++ {
+		columnConfigs = append(columnConfigs, table.ColumnConfig{
+			WidthMax: opts.ColumnWidthMax,
+			Number:   i + 1,
+		})
+	}
+	t.SetColumnConfigs(columnConfigs)
+	t.AppendHeader(header)
+	// t.SetAutoIndex(true)
+	t.SetAllowedRowLength(opts.RowWidthMax)
+	return t
+}
@@ -0,0 +1,576 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/term"
+	"sigs.k8s.io/yaml"
+
+	"github.com/nfyxhan/kubewatch/pkg/utils"
+)
+
+// tuiHistory is the number of events kept for scrolling back.
+const tuiHistory = 1000
+
+const (
+	keyUp       = "\x1b[A"
+	keyDown     = "\x1b[B"
+	keyPageUp   = "\x1b[5~"
+	keyPageDown = "\x1b[6~"
+	keyHome     = "\x1b[H"
+	keyEnd      = "\x1b[F"
+	keyEsc      = "\x1b"
+	keyEnter    = "\r"
+	keyCtrlC    = "\x03"
+	keyCtrlU    = "\x15"
+	keyBack     = "\x7f"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;?]*[a-zA-Z]")
+
+// tuiRow is a line of the event list, a change of an event or its text diff.
+type tuiRow struct {
+	event  *Event
+	change int
+}
+
+type tuiWriter struct {
+	sync.Mutex
+	in    *os.File
+	out   io.Writer
+	state *term.State
+	opts  Options
+
+	events []Event
+	// pending are the events written while paused, at most tuiHistory,
+	// dropped counts the older ones dropped.
+	pending []Event
+	dropped int
+	paused  bool
+
+	// filter is matched case insensitive against the rows, input is the
+	// filter being edited.
+	filter  string
+	editing bool
+	input   string
+
+	width    int
+	height   int
+	selected int
+	offset   int
+	follow   bool
+
+	detail       bool
+	detailOffset int
+
+	// closed is set once the terminal is restored, done is closed then.
+	closed bool
+	done   chan struct{}
+}
+
+// NewTUIWriter renders the events as an interactive list on the terminal of
+// in and out, with a detail pane showing the full objects of a change.
+func NewTUIWriter(in *os.File, out io.Writer, opts Options) (Writer, error) {
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("tui output needs a terminal")
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	t := &tuiWriter{
+		in:     in,
+		out:    out,
+		state:  state,
+		opts:   opts,
+		follow: true,
+		done:   make(chan struct{}),
+	}
+	t.resize()
+	// alternate screen and hidden cursor
+	fmt.Fprint(t.out, "\x1b[?1049h\x1b[?25l")
+	go t.readKeys()
+	go notifyResize(func() {
+		t.Lock()
+		defer t.Unlock()
+		t.resize()
+		t.render()
+	})
+	t.Lock()
+	t.render()
+	t.Unlock()
+	return t, nil
+}
+
+// Wait blocks until the user quits an interactive writer, it returns at once
+// for other writers.
+func Wait(w Writer) {
+	if t, ok := w.(*tuiWriter); ok {
+		<-t.done
+	}
+}
+
+// Done returns a channel closed when the user quits an interactive writer,
+// nil for other writers.
+func Done(w Writer) <-chan struct{} {
+	if t, ok := w.(*tuiWriter); ok {
+		return t.done
+	}
+	return nil
+}
+
+// Close restores the terminal of an interactive writer, e.g. when the watch
+// is stopped by a signal. Events written afterwards are dropped.
+func Close(w Writer) {
+	if t, ok := w.(*tuiWriter); ok {
+		t.close()
+	}
+}
+
+func (t *tuiWriter) close() {
+	t.Lock()
+	defer t.Unlock()
+	if t.closed {
+		return
+	}
+	t.closed = true
+	t.restore()
+	close(t.done)
+}
+
+func (t *tuiWriter) restore() {
+	fmt.Fprint(t.out, "\x1b[?25h\x1b[?1049l")
+	term.Restore(int(t.in.Fd()), t.state)
+}
+
+func (t *tuiWriter) resize() {
+	w, h, err := term.GetSize(int(t.in.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		w, h = t.opts.RowWidthMax, t.opts.MaxRows+4
+	}
+	t.width, t.height = w, h
+}
+
+func (t *tuiWriter) Write(e Event) error {
+	if len(e.Changes) == 0 && e.Diff == "" {
+		return nil
+	}
+	t.Lock()
+	defer t.Unlock()
+	if t.paused {
+		if len(t.pending) == tuiHistory {
+			t.pending = append(t.pending[:0], t.pending[1:]...)
+			t.dropped++
+		}
+		t.pending = append(t.pending, e)
+		return nil
+	}
+	t.append(e)
+	t.render()
+	return nil
+}
+
+func (t *tuiWriter) append(events ...Event) {
+	t.events = append(t.events, events...)
+	if n := len(t.events) - tuiHistory; n > 0 {
+		// keep the selection on the same row
+		t.selected -= len(t.rowsOf(t.events[:n]))
+		t.events = append([]Event(nil), t.events[n:]...)
+	}
+}
+
+// rows returns the list lines of all events matching the filter.
+func (t *tuiWriter) rows() []tuiRow {
+	return t.rowsOf(t.events)
+}
+
+func (t *tuiWriter) rowsOf(events []Event) []tuiRow {
+	rows := make([]tuiRow, 0, len(events))
+	for i := range events {
+		e := &events[i]
+		if e.Diff != "" {
+			if t.match(e, -1) {
+				rows = append(rows, tuiRow{event: e, change: -1})
+			}
+			continue
+		}
+		for j := range e.Changes {
+			if t.match(e, j) {
+				rows = append(rows, tuiRow{event: e, change: j})
+			}
+		}
+	}
+	return rows
+}
+
+func (t *tuiWriter) match(e *Event, change int) bool {
+	filter := t.filter
+	if t.editing {
+		filter = t.input
+	}
+	if filter == "" {
+		return true
+	}
+	s := e.Key(true)
+	if change >= 0 {
+		c := e.Changes[change]
+		s = fmt.Sprintf("%s %s %v %v %s", s, c.Path, c.From, c.To, c.Op)
+	}
+	return strings.Contains(strings.ToLower(s), strings.ToLower(filter))
+}
+
+func (t *tuiWriter) readKeys() {
+	buf := make([]byte, 64)
+	for {
+		n, err := t.in.Read(buf)
+		if err != nil {
+			return
+		}
+		keys := []string{string(buf[:n])}
+		if buf[0] != keyEsc[0] {
+			// typed or pasted text
+			keys = strings.Split(string(buf[:n]), "")
+		}
+		for _, k := range keys {
+			t.Lock()
+			quit := t.handleKey(k)
+			t.render()
+			t.Unlock()
+			if quit {
+				t.close()
+				return
+			}
+		}
+	}
+}
+
+// handleKey applies a key press and reports whether to quit.
+func (t *tuiWriter) handleKey(k string) bool {
+	if t.editing {
+		switch k {
+		case keyEnter:
+			t.filter, t.editing = t.input, false
+		case keyEsc:
+			t.editing = false
+		case keyBack, "\b":
+			if r := []rune(t.input); len(r) > 0 {
+				t.input = string(r[:len(r)-1])
+			}
+		case keyCtrlU:
+			t.input = ""
+		case keyCtrlC:
+			return true
+		default:
+			if r := []rune(k); len(r) == 1 && unicode.IsPrint(r[0]) {
+				t.input += k
+			}
+		}
+		t.clampSelection()
+		return false
+	}
+	rows := len(t.rows())
+	page := t.listHeight()
+	if t.detail {
+		// the arrow keys scroll the detail pane, [ and ] select other rows
+		switch k {
+		case keyUp, "k":
+			t.detailOffset--
+		case keyDown, "j":
+			t.detailOffset++
+		case keyPageUp:
+			t.detailOffset -= t.detailHeight()
+		case keyPageDown, " ":
+			t.detailOffset += t.detailHeight()
+		case "[":
+			t.move(-1, rows)
+		case "]":
+			t.move(1, rows)
+		case keyEsc, keyEnter:
+			t.detail = false
+		case "q", keyCtrlC:
+			return true
+		}
+		if t.detailOffset < 0 {
+			t.detailOffset = 0
+		}
+		return false
+	}
+	switch k {
+	case "q", keyCtrlC:
+		return true
+	case keyUp, "k":
+		t.move(-1, rows)
+	case keyDown, "j":
+		t.move(1, rows)
+	case keyPageUp, "\x02":
+		t.move(-page, rows)
+	case keyPageDown, "\x06":
+		t.move(page, rows)
+	case keyHome, "\x1b[1~", "\x1bOH", "g":
+		t.move(-rows, rows)
+	case keyEnd, "\x1b[4~", "\x1bOF", "G":
+		t.move(rows, rows)
+	case keyEnter:
+		if rows > 0 {
+			t.detail, t.detailOffset = true, 0
+		}
+	case " ", "p":
+		t.paused = !t.paused
+		if !t.paused {
+			t.append(t.pending...)
+			t.pending, t.dropped = nil, 0
+		}
+	case "/":
+		t.editing, t.input = true, t.filter
+	case "c":
+		t.filter = ""
+		t.clampSelection()
+	}
+	return false
+}
+
+// move moves the selection by n rows, following new events at the end.
+func (t *tuiWriter) move(n, rows int) {
+	t.selected += n
+	t.detailOffset = 0
+	t.clampSelection()
+	t.follow = t.selected >= rows-1
+}
+
+func (t *tuiWriter) clampSelection() {
+	rows := len(t.rows())
+	if t.selected >= rows {
+		t.selected = rows - 1
+	}
+	if t.selected < 0 {
+		t.selected = 0
+	}
+}
+
+func (t *tuiWriter) listHeight() int {
+	h := t.height - 3
+	if t.detail {
+		h = h / 3
+	}
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+func (t *tuiWriter) detailHeight() int {
+	h := t.height - 3 - t.listHeight() - 1
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+func (t *tuiWriter) render() {
+	if t.closed {
+		return
+	}
+	rows := t.rows()
+	t.clampSelection()
+	if t.follow && len(rows) > 0 {
+		t.selected = len(rows) - 1
+	}
+	listHeight := t.listHeight()
+	if t.selected < t.offset {
+		t.offset = t.selected
+	}
+	if t.selected >= t.offset+listHeight {
+		t.offset = t.selected - listHeight + 1
+	}
+	if t.offset > len(rows)-listHeight {
+		t.offset = len(rows) - listHeight
+	}
+	if t.offset < 0 {
+		t.offset = 0
+	}
+
+	lines := make([]string, 0, t.height)
+	status := fmt.Sprintf(" kubewatch  %d events  %d/%d rows", len(t.events), t.selected+1, len(rows))
+	if len(rows) == 0 {
+		status = fmt.Sprintf(" kubewatch  %d events  no rows", len(t.events))
+	}
+	if t.paused {
+		status += fmt.Sprintf("  PAUSED (%d new", len(t.pending))
+		if t.dropped > 0 {
+			status += fmt.Sprintf(", %d dropped", t.dropped)
+		}
+		status += ")"
+	}
+	if t.filter != "" {
+		status += "  filter: " + t.filter
+	}
+	lines = append(lines, "\x1b[7m"+fit(status, t.width)+"\x1b[0m")
+	lines = append(lines, utils.ColorString(utils.Blue, "%s", t.formatRow("time", "object", "path", "from", "to", "op")))
+	for i := t.offset; i < t.offset+listHeight; i++ {
+		if i >= len(rows) {
+			lines = append(lines, "")
+			continue
+		}
+		l := t.formatListRow(rows[i])
+		switch {
+		case i == t.selected:
+			l = "\x1b[7m" + l + "\x1b[0m"
+		case rows[i].event.ObjectOp() == OpCreated:
+			l = utils.ColorString(utils.Green, "%s", l)
+		case rows[i].event.ObjectOp() == OpDeleted:
+			l = utils.ColorString(utils.Red, "%s", l)
+		}
+		lines = append(lines, l)
+	}
+	if t.detail && t.selected < len(rows) {
+		lines = append(lines, utils.ColorString(utils.Blue, "%s", strings.Repeat("─", t.width)))
+		detail := t.detailLines(rows[t.selected])
+		if max := len(detail) - t.detailHeight(); t.detailOffset > max {
+			t.detailOffset = max
+		}
+		if t.detailOffset < 0 {
+			t.detailOffset = 0
+		}
+		for i := t.detailOffset; i < t.detailOffset+t.detailHeight(); i++ {
+			l := ""
+			if i < len(detail) {
+				l = truncateANSI(detail[i], t.width)
+			}
+			lines = append(lines, l)
+		}
+	}
+	switch {
+	case t.editing:
+		lines = append(lines, fit("/"+t.input+"█", t.width))
+	case t.detail:
+		lines = append(lines, fit(" ↑/↓ scroll  [/] previous/next  esc close  q quit", t.width))
+	default:
+		lines = append(lines, fit(" ↑/↓ select  enter details  space pause  / filter  c clear filter  q quit", t.width))
+	}
+	bf := &strings.Builder{}
+	bf.WriteString("\x1b[H")
+	for i, l := range lines {
+		if i > 0 {
+			bf.WriteString("\r\n")
+		}
+		bf.WriteString(l)
+		bf.WriteString("\x1b[K")
+	}
+	bf.WriteString("\x1b[J")
+	io.WriteString(t.out, bf.String())
+}
+
+func (t *tuiWriter) formatListRow(r tuiRow) string {
+	e := r.event
+	key := e.Key(true)
+	now := e.Time.Local().Format("15:04:05.000")
+	if r.change < 0 {
+		return t.formatRow(now, key, "<diff>", "", "", "update")
+	}
+	c := e.Changes[r.change]
+	if e.ObjectOp() != "" {
+		return t.formatRow(now, key, "", fmt.Sprint(emptyNil(c.From)), fmt.Sprint(emptyNil(c.To)), c.Op)
+	}
+	return t.formatRow(now, key, c.Path, valueString(c.From), valueString(c.To), c.Op)
+}
+
+func (t *tuiWriter) formatRow(now, key, path, from, to, op string) string {
+	const timeWidth, opWidth = 12, 7
+	rest := t.width - timeWidth - opWidth - 5
+	if rest < 20 {
+		rest = 20
+	}
+	keyWidth, pathWidth := rest*3/10, rest*3/10
+	valueWidth := (rest - keyWidth - pathWidth) / 2
+	return strings.Join([]string{
+		fit(now, timeWidth),
+		fit(key, keyWidth),
+		fit(path, pathWidth),
+		fit(from, valueWidth),
+		fit(to, valueWidth),
+		fit(op, opWidth),
+	}, " ")
+}
+
+// detailLines shows the selected change and the full old and new objects
+// side by side.
+func (t *tuiWriter) detailLines(r tuiRow) []string {
+	e := r.event
+	var lines []string
+	if r.change < 0 {
+		lines = append(lines, fmt.Sprintf("%s %s", e.Time.Local().Format("15:04:05.000"), e.Key(true)))
+		return append(lines, strings.Split(e.Diff, "\n")...)
+	}
+	c := e.Changes[r.change]
+	lines = append(lines,
+		fmt.Sprintf("%s %s", e.Time.Local().Format("15:04:05.000"), e.Key(true)),
+		fmt.Sprintf("%s %s: %s → %s", c.Op, c.Path,
+			utils.ColorString(utils.Red, "%s", valueString(c.From)),
+			utils.ColorString(utils.Green, "%s", valueString(c.To))),
+		"",
+	)
+	if e.Old == nil && e.New == nil {
+		return lines
+	}
+	from, err := objectYAML(e.Old)
+	if err != nil {
+		return append(lines, err.Error())
+	}
+	to, err := objectYAML(e.New)
+	if err != nil {
+		return append(lines, err.Error())
+	}
+	s := sideBySide(from, to, t.width, strings.Count(from, "\n")+strings.Count(to, "\n"), true)
+	if s == "" {
+		s = to
+	}
+	return append(lines, strings.Split(strings.TrimSuffix(s, "\n"), "\n")...)
+}
+
+// objectYAML renders an object, created objects have no old and deleted
+// objects no new object.
+func objectYAML(obj interface{}) (string, error) {
+	if v := reflect.ValueOf(obj); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return "", nil
+	}
+	b, err := yaml.Marshal(obj)
+	return string(b), err
+}
+
+func valueString(v interface{}) string {
+	if v == nil {
+		return "<nil>"
+	}
+	return strings.ReplaceAll(fmt.Sprint(v), "\n", " ")
+}
+
+// truncateANSI cuts s to width visible runes, keeping its escape sequences.
+func truncateANSI(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	bf := &strings.Builder{}
+	visible := 0
+	for len(s) > 0 {
+		if loc := ansiEscape.FindStringIndex(s); loc != nil && loc[0] == 0 {
+			bf.WriteString(s[:loc[1]])
+			s = s[loc[1]:]
+			continue
+		}
+		r := []rune(s)[0]
+		if visible == width {
+			bf.WriteString("\x1b[0m")
+			break
+		}
+		bf.WriteRune(r)
+		visible++
+		s = s[len(string(r)):]
+	}
+	return bf.String()
+}
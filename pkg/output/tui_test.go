@@ -0,0 +1,110 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/term"
+)
+
+func TestTUIWriter(t *testing.T) {
+	w := &tuiWriter{out: io.Discard, width: 80, height: 20, follow: true}
+	for _, name := range []string{"web", "db", "web"} {
+		w.Write(Event{Kind: "Pod", Name: name, Changes: []Change{{Path: "status/phase", From: "Pending", To: "Running", Op: "update"}}})
+	}
+	if w.selected != 2 {
+		t.Fatalf("selected = %d, want the last row", w.selected)
+	}
+	w.handleKey(keyUp)
+	w.handleKey(" ")
+	w.Write(Event{Kind: "Pod", Name: "cache", Changes: []Change{{Path: "spec/replicas", From: 1, To: 2, Op: "update"}}})
+	if len(w.rows()) != 3 || len(w.pending) != 1 || w.selected != 1 {
+		t.Fatalf("paused: rows = %d, pending = %d, selected = %d", len(w.rows()), len(w.pending), w.selected)
+	}
+	w.handleKey(" ")
+	if len(w.rows()) != 4 || w.selected != 1 {
+		t.Fatalf("resumed: rows = %d, selected = %d", len(w.rows()), w.selected)
+	}
+	for _, k := range []string{"/", "w", "e", "b", keyEnter} {
+		w.handleKey(k)
+	}
+	if w.filter != "web" || len(w.rows()) != 2 {
+		t.Fatalf("filter = %q, rows = %d", w.filter, len(w.rows()))
+	}
+	w.handleKey(keyEnter)
+	if !w.detail {
+		t.Fatalf("detail pane not opened")
+	}
+	w.render()
+}
+
+// TestTUIWriterClose checks quitting restores the terminal and returns to
+// the command instead of exiting.
+func TestTUIWriterClose(t *testing.T) {
+	var out bytes.Buffer
+	w := &tuiWriter{out: &out, state: &term.State{}, width: 80, height: 20, follow: true, done: make(chan struct{})}
+	if Done(NewJSONWriter(io.Discard, false)) != nil {
+		t.Errorf("Done() of a non interactive writer is not nil")
+	}
+	if !w.handleKey("q") {
+		t.Fatalf("q doesn't quit")
+	}
+	Close(w)
+	select {
+	case <-Done(w):
+	default:
+		t.Fatalf("Done() not closed after quitting")
+	}
+	Wait(w)
+	n := out.Len()
+	w.Write(Event{Kind: "Pod", Name: "web", Changes: []Change{{Path: "status/phase", To: "Running", Op: "update"}}})
+	if out.Len() != n {
+		t.Errorf("rendered after quitting: %q", out.String()[n:])
+	}
+	// closing again, e.g. by the command, is a no-op
+	Close(w)
+}
+
+func TestTUIWriterPausedLimit(t *testing.T) {
+	var out bytes.Buffer
+	w := &tuiWriter{out: &out, width: 80, height: 20, follow: true}
+	w.handleKey(" ")
+	for i := 0; i < tuiHistory+5; i++ {
+		w.Write(Event{Kind: "Pod", Name: fmt.Sprintf("web-%d", i), Changes: []Change{{Path: "status/phase", To: "Running", Op: "update"}}})
+	}
+	if len(w.pending) != tuiHistory || w.dropped != 5 {
+		t.Fatalf("pending = %d, dropped = %d, want %d, 5", len(w.pending), w.dropped, tuiHistory)
+	}
+	if name := w.pending[0].Name; name != "web-5" {
+		t.Errorf("oldest pending = %s, want web-5", name)
+	}
+	out.Reset()
+	w.render()
+	if !strings.Contains(out.String(), fmt.Sprintf("PAUSED (%d new, 5 dropped)", tuiHistory)) {
+		t.Errorf("status line misses the dropped events: %q", out.String())
+	}
+	w.handleKey(" ")
+	if len(w.pending) != 0 || w.dropped != 0 || len(w.events) != tuiHistory {
+		t.Errorf("resumed: pending = %d, dropped = %d, events = %d", len(w.pending), w.dropped, len(w.events))
+	}
+}
+
+func TestTruncateANSI(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc\x1b[0m"},
+		{"\x1b[31mabcdef\x1b[0m", 2, "\x1b[31mab\x1b[0m"},
+	}
+	for _, tt := range tests {
+		if got := truncateANSI(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncateANSI(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package output
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize calls fn whenever the terminal is resized.
+func notifyResize(fn func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+	for range ch {
+		fn()
+	}
+}
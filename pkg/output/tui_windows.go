@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package output
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// notifyResize calls fn whenever the terminal is resized. Windows has no
+// SIGWINCH, so the size is polled.
+func notifyResize(fn func()) {
+	fd := int(os.Stdout.Fd())
+	w, h, _ := term.GetSize(fd)
+	for range time.Tick(500 * time.Millisecond) {
+		ww, hh, err := term.GetSize(fd)
+		if err != nil || (ww == w && hh == h) {
+			continue
+		}
+		w, h = ww, hh
+		fn()
+	}
+}
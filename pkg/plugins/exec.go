@@ -0,0 +1,325 @@
+package plugins
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// ExecPrefix is the name prefix of the executables loaded as exec plugins.
+const ExecPrefix = "kubewatch-plugin-"
+
+// ExecTimeout is how long a response of an exec plugin is waited for, the
+// plugin is stopped if it takes longer.
+var ExecTimeout = 5 * time.Second
+
+// maxMessageSize is the maximum size of a message, objects can be large.
+const maxMessageSize = 64 << 20
+
+// ExecPlugin is a plugin running as a process, exchanging the messages of
+// the protocol over its stdin and stdout. Its hooks are skipped once the
+// process failed.
+type ExecPlugin struct {
+	path      string
+	info      Info
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	enc       *json.Encoder
+	responses chan Response
+	// done is closed when the plugin closed its stdout, readErr is the
+	// reason.
+	done    chan struct{}
+	readErr error
+
+	sync.Mutex
+	id     int64
+	failed error
+}
+
+// StartExec starts an exec plugin and checks its protocol version.
+func StartExec(cmd *exec.Cmd) (*ExecPlugin, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	cmd.Env = append(os.Environ(), "KUBEWATCH_PLUGIN_PROTOCOL="+ProtocolVersion)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &ExecPlugin{
+		path:      cmd.Path,
+		cmd:       cmd,
+		stdin:     stdin,
+		enc:       json.NewEncoder(stdin),
+		responses: make(chan Response, 1),
+		done:      make(chan struct{}),
+	}
+	go p.read(stdout)
+	if err := p.call(MethodHandshake, Handshake{ProtocolVersion: ProtocolVersion}, &p.info); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("handshake: %v", err)
+	}
+	if p.info.ProtocolVersion != ProtocolVersion {
+		p.Close()
+		return nil, fmt.Errorf("protocol version %q is not supported, want %s", p.info.ProtocolVersion, ProtocolVersion)
+	}
+	return p, nil
+}
+
+func (p *ExecPlugin) read(stdout io.Reader) {
+	defer close(p.done)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(nil, maxMessageSize)
+	for scanner.Scan() {
+		var r Response
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			p.readErr = fmt.Errorf("invalid response: %v", err)
+			return
+		}
+		p.responses <- r
+	}
+	p.readErr = scanner.Err()
+	if p.readErr == nil {
+		p.readErr = io.EOF
+	}
+}
+
+// Info returns the info of the handshake.
+func (p *ExecPlugin) Info() Info {
+	return p.info
+}
+
+// Close stops the plugin, it exits when its stdin is closed.
+func (p *ExecPlugin) Close() error {
+	p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(ExecTimeout):
+		p.cmd.Process.Kill()
+	}
+	return p.cmd.Wait()
+}
+
+// call sends a request and decodes the result of its response.
+func (p *ExecPlugin) call(method string, params, result interface{}) error {
+	p.Lock()
+	defer p.Unlock()
+	if p.failed != nil {
+		return p.failed
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	p.id++
+	if err := p.enc.Encode(Request{ID: p.id, Method: method, Params: b}); err != nil {
+		return p.fail(err)
+	}
+	select {
+	case r := <-p.responses:
+		if r.ID != p.id {
+			return p.fail(fmt.Errorf("response %d to request %d", r.ID, p.id))
+		}
+		if r.Error != "" {
+			return fmt.Errorf("%s: %s", method, r.Error)
+		}
+		return json.Unmarshal(r.Result, result)
+	case <-p.done:
+		return p.fail(p.readErr)
+	case <-time.After(ExecTimeout):
+		return p.fail(fmt.Errorf("no response to %s within %s", method, ExecTimeout))
+	}
+}
+
+// notify sends a notification.
+func (p *ExecPlugin) notify(method string, params interface{}) error {
+	p.Lock()
+	defer p.Unlock()
+	if p.failed != nil {
+		return p.failed
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	if err := p.enc.Encode(Request{Method: method, Params: b}); err != nil {
+		return p.fail(err)
+	}
+	return nil
+}
+
+// fail stops the plugin after an error of the protocol, its hooks are
+// skipped from now on.
+func (p *ExecPlugin) fail(err error) error {
+	p.failed = fmt.Errorf("plugin %s failed: %v", p.path, err)
+	fmt.Fprintf(os.Stderr, "%v, skipping its hooks\n", p.failed)
+	p.cmd.Process.Kill()
+	return p.failed
+}
+
+func (p *ExecPlugin) hook(method string) bool {
+	return slices.Contains(p.info.Hooks, method)
+}
+
+func (p *ExecPlugin) logError(method string, obj client.Object, err error) {
+	fmt.Fprintf(os.Stderr, "plugin %s: %s %s/%s: %v\n", p.info.Name, method, obj.GetNamespace(), obj.GetName(), err)
+}
+
+// AddToScheme registers nothing, exec plugins get unstructured objects.
+func (p *ExecPlugin) AddToScheme(s *runtime.Scheme) error {
+	return nil
+}
+
+// ListObjects returns no typed objects.
+func (p *ExecPlugin) ListObjects() []Object {
+	return nil
+}
+
+// Handles reports whether the plugin selects the kind.
+func (p *ExecPlugin) Handles(gvk schema.GroupVersionKind) bool {
+	if len(p.info.Kinds) == 0 {
+		return true
+	}
+	for _, k := range p.info.Kinds {
+		if k.matches(gvk) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ExecPlugin) Normalize(obj client.Object) client.Object {
+	if !p.hook(MethodNormalize) {
+		return obj
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		p.logError(MethodNormalize, obj, err)
+		return obj
+	}
+	var result ObjectResult
+	if err := p.call(MethodNormalize, ObjectParams{Object: b}, &result); err != nil {
+		p.logError(MethodNormalize, obj, err)
+		return obj
+	}
+	normalized := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+	if err := json.Unmarshal(result.Object, normalized); err != nil {
+		p.logError(MethodNormalize, obj, err)
+		return obj
+	}
+	normalized.GetObjectKind().SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+	return normalized
+}
+
+func (p *ExecPlugin) Summarize(obj client.Object) (string, bool) {
+	if !p.hook(MethodSummarize) {
+		return "", false
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		p.logError(MethodSummarize, obj, err)
+		return "", false
+	}
+	var result SummaryResult
+	if err := p.call(MethodSummarize, ObjectParams{Object: b}, &result); err != nil {
+		p.logError(MethodSummarize, obj, err)
+		return "", false
+	}
+	return result.Summary, result.OK
+}
+
+func (p *ExecPlugin) Metrics(obj client.Object) []Sample {
+	if !p.hook(MethodMetrics) {
+		return nil
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		p.logError(MethodMetrics, obj, err)
+		return nil
+	}
+	var result MetricsResult
+	if err := p.call(MethodMetrics, ObjectParams{Object: b}, &result); err != nil {
+		p.logError(MethodMetrics, obj, err)
+		return nil
+	}
+	return result.Samples
+}
+
+func (p *ExecPlugin) Filter(objOld, objNew client.Object) bool {
+	if !p.hook(MethodFilter) {
+		return true
+	}
+	obj := objNew
+	if obj == nil {
+		obj = objOld
+	}
+	var params FilterParams
+	var err error
+	if objOld != nil {
+		params.Old, err = json.Marshal(objOld)
+	}
+	if err == nil && objNew != nil {
+		params.New, err = json.Marshal(objNew)
+	}
+	if err != nil {
+		p.logError(MethodFilter, obj, err)
+		return true
+	}
+	var result FilterResult
+	if err := p.call(MethodFilter, params, &result); err != nil {
+		p.logError(MethodFilter, obj, err)
+		return true
+	}
+	return result.Pass
+}
+
+// Write sends the records of an event to the plugin.
+func (p *ExecPlugin) Write(e output.Event) error {
+	if !p.hook(MethodRecords) {
+		return nil
+	}
+	return p.notify(MethodRecords, RecordsParams{Records: e.Records()})
+}
+
+// CloseExec stops the loaded exec plugins, e.g. on exit, so they receive all
+// records before their stdin is closed.
+func CloseExec() {
+	for _, l := range loaded {
+		if p, ok := l.Plugin.(*ExecPlugin); ok {
+			p.Close()
+		}
+	}
+}
+
+// Writers returns the exec plugins receiving the records of the reported
+// events.
+func Writers() []output.Writer {
+	load()
+	var writers []output.Writer
+	for _, l := range loaded {
+		if p, ok := l.Plugin.(*ExecPlugin); ok && p.hook(MethodRecords) {
+			writers = append(writers, p)
+		}
+	}
+	return writers
+}
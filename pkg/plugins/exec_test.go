@@ -0,0 +1,83 @@
+package plugins
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// execTestPlugin drops the labels of objects and passes only objects named
+// web.
+type execTestPlugin struct{}
+
+func (p *execTestPlugin) Normalize(obj client.Object) client.Object {
+	obj.SetLabels(nil)
+	return obj
+}
+
+func (p *execTestPlugin) Filter(objOld, objNew client.Object) bool {
+	return objNew != nil && objNew.GetName() == "web"
+}
+
+func (p *execTestPlugin) HandleRecords(records []output.Record) {
+	os.Stderr.WriteString("records of " + records[0].Name + "\n")
+}
+
+// TestExecPluginProcess is run by TestExecPlugin as plugin process.
+func TestExecPluginProcess(t *testing.T) {
+	if os.Getenv("KUBEWATCH_PLUGIN_PROTOCOL") == "" {
+		t.Skip("run as plugin by TestExecPlugin")
+	}
+	info := Info{Name: "test", Version: "1.0.0", Kinds: []Kind{{Group: "apps"}}}
+	if err := ServeStdio(info, &execTestPlugin{}); err != nil {
+		t.Fatal(err)
+	}
+	os.Exit(0)
+}
+
+func TestExecPlugin(t *testing.T) {
+	var stderr strings.Builder
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExecPluginProcess$")
+	cmd.Stderr = &stderr
+	p, err := StartExec(cmd)
+	if err != nil {
+		t.Fatalf("StartExec() error = %v", err)
+	}
+	if info := p.Info(); info.Name != "test" || strings.Join(info.Hooks, ",") != "normalize,filter,records" {
+		t.Errorf("Info() = %+v", info)
+	}
+	deploy := &unstructured.Unstructured{}
+	deploy.SetAPIVersion("apps/v1")
+	deploy.SetKind("Deployment")
+	deploy.SetName("web")
+	deploy.SetLabels(map[string]string{"app": "web"})
+	if !p.Handles(deploy.GroupVersionKind()) || p.Handles(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}) {
+		t.Errorf("Handles() doesn't select the apps group only")
+	}
+	normalized := p.Normalize(deploy)
+	if len(normalized.GetLabels()) != 0 || len(deploy.GetLabels()) != 1 || normalized.GetObjectKind().GroupVersionKind().Kind != "Deployment" {
+		t.Errorf("Normalize() = %#v", normalized)
+	}
+	if !p.Filter(nil, deploy) || p.Filter(deploy, nil) {
+		t.Errorf("Filter() doesn't pass only objects named web")
+	}
+	if samples := p.Metrics(deploy); samples != nil {
+		t.Errorf("Metrics() = %v, want nothing of a plugin without the hook", samples)
+	}
+	if err := p.Write(output.Event{Name: "web", Changes: []output.Change{{Path: "spec/replicas", To: 2}}}); err != nil {
+		t.Errorf("Write() error = %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if !strings.Contains(stderr.String(), "records of web") {
+		t.Errorf("records were not handled, stderr %q", stderr.String())
+	}
+}
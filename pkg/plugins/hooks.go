@@ -0,0 +1,176 @@
+package plugins
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The hooks below are optional interfaces of a Plugin. They are called for
+// the kinds of the objects of the plugin, or for the kinds it handles if it
+// implements KindHandler, so they also apply to unstructured objects.
+
+// KindHandler selects the kinds the hooks of a plugin are called for.
+type KindHandler interface {
+	Handles(gvk schema.GroupVersionKind) bool
+}
+
+// Normalizer cleans objects before they are diffed, e.g. drops timestamps
+// updated by every heartbeat. It must return a copy instead of modifying
+// the object, which is shared with the cache.
+type Normalizer interface {
+	Normalize(obj client.Object) client.Object
+}
+
+// Summarizer returns the summary of created and deleted objects, false to
+// use the summary fields.
+type Summarizer interface {
+	Summarize(obj client.Object) (string, bool)
+}
+
+// Sample is a metric value of an object.
+type Sample struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// MetricExtractor returns the metric values of an object, they are exported
+// as plugin_values.
+type MetricExtractor interface {
+	Metrics(obj client.Object) []Sample
+}
+
+// EventFilter reports whether an event is reported, objOld is nil for
+// created objects and objNew for deleted ones.
+type EventFilter interface {
+	Filter(objOld, objNew client.Object) bool
+}
+
+// Hooks are the hooks of the plugins handling a kind, in the order the
+// plugins were loaded.
+type Hooks struct {
+	Normalizers      []Normalizer
+	Summarizers      []Summarizer
+	MetricExtractors []MetricExtractor
+	EventFilters     []EventFilter
+}
+
+var hooks = struct {
+	sync.Mutex
+	kinds map[schema.GroupVersionKind]Hooks
+}{kinds: make(map[schema.GroupVersionKind]Hooks)}
+
+// HooksFor returns the hooks of the loaded plugins for a kind.
+func HooksFor(gvk schema.GroupVersionKind) Hooks {
+	load()
+	hooks.Lock()
+	defer hooks.Unlock()
+	if h, ok := hooks.kinds[gvk]; ok {
+		return h
+	}
+	var h Hooks
+	for _, l := range loaded {
+		if !l.handles(gvk) {
+			continue
+		}
+		if n, ok := l.Plugin.(Normalizer); ok {
+			h.Normalizers = append(h.Normalizers, n)
+		}
+		if s, ok := l.Plugin.(Summarizer); ok {
+			h.Summarizers = append(h.Summarizers, s)
+		}
+		if e, ok := l.Plugin.(MetricExtractor); ok {
+			h.MetricExtractors = append(h.MetricExtractors, e)
+		}
+		if f, ok := l.Plugin.(EventFilter); ok {
+			h.EventFilters = append(h.EventFilters, f)
+		}
+	}
+	hooks.kinds[gvk] = h
+	return h
+}
+
+// resetHooks drops the hooks of all kinds when a plugin is added.
+func resetHooks() {
+	hooks.Lock()
+	hooks.kinds = make(map[schema.GroupVersionKind]Hooks)
+	hooks.Unlock()
+}
+
+// Hooks returns the names of the hooks the plugin implements.
+func (l Loaded) Hooks() []string {
+	if p, ok := l.Plugin.(*ExecPlugin); ok {
+		return p.info.Hooks
+	}
+	return hookNames(l.Plugin)
+}
+
+func hookNames(p interface{}) []string {
+	var names []string
+	if _, ok := p.(Normalizer); ok {
+		names = append(names, MethodNormalize)
+	}
+	if _, ok := p.(Summarizer); ok {
+		names = append(names, MethodSummarize)
+	}
+	if _, ok := p.(MetricExtractor); ok {
+		names = append(names, MethodMetrics)
+	}
+	if _, ok := p.(EventFilter); ok {
+		names = append(names, MethodFilter)
+	}
+	return names
+}
+
+func (l Loaded) handles(gvk schema.GroupVersionKind) bool {
+	if h, ok := l.Plugin.(KindHandler); ok {
+		return h.Handles(gvk)
+	}
+	for _, o := range l.Objects {
+		if k, err := o.GVK(); err == nil && k == gvk {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize returns the object cleaned by all normalizers.
+func (h Hooks) Normalize(obj client.Object) client.Object {
+	if obj == nil {
+		return nil
+	}
+	for _, n := range h.Normalizers {
+		obj = n.Normalize(obj)
+	}
+	return obj
+}
+
+// Summarize returns the summary of the first summarizer which has one.
+func (h Hooks) Summarize(obj client.Object) (string, bool) {
+	for _, s := range h.Summarizers {
+		if summary, ok := s.Summarize(obj); ok {
+			return summary, true
+		}
+	}
+	return "", false
+}
+
+// Metrics returns the samples of all metric extractors.
+func (h Hooks) Metrics(obj client.Object) []Sample {
+	var samples []Sample
+	for _, e := range h.MetricExtractors {
+		samples = append(samples, e.Metrics(obj)...)
+	}
+	return samples
+}
+
+// Filter reports whether all event filters pass the event.
+func (h Hooks) Filter(objOld, objNew client.Object) bool {
+	for _, f := range h.EventFilters {
+		if !f.Filter(objOld, objNew) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package plugins
+
+import (
+	"fmt"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type hookPlugin struct{}
+
+func (p *hookPlugin) AddToScheme(s *runtime.Scheme) error {
+	return appsv1.AddToScheme(s)
+}
+
+func (p *hookPlugin) ListObjects() []Object {
+	return []Object{{Name: "deployment", Object: &appsv1.Deployment{}, ObjectList: &appsv1.DeploymentList{}}}
+}
+
+func (p *hookPlugin) Normalize(obj client.Object) client.Object {
+	d := obj.(*appsv1.Deployment).DeepCopy()
+	d.Status.ObservedGeneration = 0
+	return d
+}
+
+func (p *hookPlugin) Summarize(obj client.Object) (string, bool) {
+	return fmt.Sprintf("replicas=%d", *obj.(*appsv1.Deployment).Spec.Replicas), true
+}
+
+func (p *hookPlugin) Metrics(obj client.Object) []Sample {
+	return []Sample{{Name: "unavailable", Value: float64(obj.(*appsv1.Deployment).Status.UnavailableReplicas)}}
+}
+
+func (p *hookPlugin) Filter(objOld, objNew client.Object) bool {
+	return objNew != nil
+}
+
+// kindPlugin handles all kinds of the core group without typed objects.
+type kindPlugin struct{}
+
+func (p *kindPlugin) AddToScheme(s *runtime.Scheme) error { return nil }
+
+func (p *kindPlugin) ListObjects() []Object { return nil }
+
+func (p *kindPlugin) Handles(gvk schema.GroupVersionKind) bool { return gvk.Group == "" }
+
+func (p *kindPlugin) Filter(objOld, objNew client.Object) bool { return false }
+
+func TestHooksFor(t *testing.T) {
+	for _, p := range []Plugin{&hookPlugin{}, &kindPlugin{}} {
+		if err := AddPlugin(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := HooksFor(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+	if len(h.Normalizers) != 1 || len(h.Summarizers) != 1 || len(h.MetricExtractors) != 1 || len(h.EventFilters) != 1 {
+		t.Fatalf("HooksFor(Deployment) = %+v", h)
+	}
+	replicas := int32(3)
+	d := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: &replicas}}
+	d.Status.ObservedGeneration = 2
+	d.Status.UnavailableReplicas = 1
+	if n := h.Normalize(d).(*appsv1.Deployment); n.Status.ObservedGeneration != 0 || d.Status.ObservedGeneration != 2 {
+		t.Errorf("Normalize() = %d, object %d", n.Status.ObservedGeneration, d.Status.ObservedGeneration)
+	}
+	if s, ok := h.Summarize(d); !ok || s != "replicas=3" {
+		t.Errorf("Summarize() = %q, %v", s, ok)
+	}
+	if samples := h.Metrics(d); len(samples) != 1 || samples[0].Value != 1 {
+		t.Errorf("Metrics() = %+v", samples)
+	}
+	if !h.Filter(nil, d) || h.Filter(d, nil) {
+		t.Errorf("Filter() doesn't pass only created and updated objects")
+	}
+
+	h = HooksFor(corev1.SchemeGroupVersion.WithKind("Pod"))
+	if len(h.EventFilters) != 1 || len(h.Normalizers) != 0 || h.Filter(nil, &corev1.Pod{}) {
+		t.Errorf("HooksFor(Pod) = %+v", h)
+	}
+	if h := HooksFor(appsv1.SchemeGroupVersion.WithKind("StatefulSet")); !h.Filter(nil, &appsv1.StatefulSet{}) || len(h.Normalizers) != 0 {
+		t.Errorf("HooksFor(StatefulSet) = %+v", h)
+	}
+}
@@ -0,0 +1,187 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"runtime"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// APIVersion is the version of the plugin API, the Plugin interface with
+// its hooks and the protocol of exec plugins.
+const APIVersion = ProtocolVersion
+
+// PathEnv lists the directories plugins are loaded from, separated like
+// PATH.
+const PathEnv = "KUBEWATCH_PLUGIN_PATH"
+
+// Dirs are the directories plugins are loaded from.
+var Dirs = DefaultDirs()
+
+// DefaultDirs returns the directories of KUBEWATCH_PLUGIN_PATH, or ./plugins
+// and the plugins directory next to the binary.
+func DefaultDirs() []string {
+	if env := os.Getenv(PathEnv); env != "" {
+		return filepath.SplitList(env)
+	}
+	dirs := []string{"plugins"}
+	if exePath, err := os.Executable(); err == nil {
+		if binaryPath, err := filepath.EvalSymlinks(filepath.Dir(exePath)); err == nil {
+			dirs = append(dirs, filepath.Join(binaryPath, "..", "plugins"))
+		}
+	}
+	return dirs
+}
+
+// Manifest describes a plugin, it is read from the file of the plugin with
+// the extension .yaml, e.g. appsv1.yaml for appsv1.so.
+type Manifest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// APIVersion is the plugin API the plugin is built for.
+	APIVersion string `json:"apiVersion"`
+	// GoVersion is the toolchain a Go plugin is built with, it must be the
+	// toolchain of kubewatch.
+	GoVersion string `json:"goVersion,omitempty"`
+}
+
+// ManifestPath returns the path of the manifest of a plugin file.
+func ManifestPath(path string) string {
+	return strings.TrimSuffix(path, ".so") + ".yaml"
+}
+
+// ReadManifest reads and checks the manifest of a plugin file.
+func ReadManifest(path string) (Manifest, error) {
+	var m Manifest
+	b, err := os.ReadFile(ManifestPath(path))
+	if err != nil {
+		return m, fmt.Errorf("no manifest: %v", err)
+	}
+	if err := yaml.UnmarshalStrict(b, &m); err != nil {
+		return m, fmt.Errorf("invalid manifest %s: %v", ManifestPath(path), err)
+	}
+	return m, m.validate(path)
+}
+
+func (m Manifest) validate(path string) error {
+	if m.Name == "" || m.Version == "" {
+		return fmt.Errorf("manifest %s needs a name and a version", ManifestPath(path))
+	}
+	if m.APIVersion != APIVersion {
+		return fmt.Errorf("plugin %s %s requires plugin API %q, kubewatch supports %s", m.Name, m.Version, m.APIVersion, APIVersion)
+	}
+	if isGoPlugin(path) && m.GoVersion != "" && m.GoVersion != runtime.Version() {
+		return fmt.Errorf("plugin %s %s is built with %s, kubewatch with %s", m.Name, m.Version, m.GoVersion, runtime.Version())
+	}
+	return nil
+}
+
+func isGoPlugin(path string) bool {
+	return strings.HasSuffix(path, ".so")
+}
+
+// Find returns the plugin files of the directories, Go plugins ending with
+// .so and executables named kubewatch-plugin-*, each file once.
+func Find(dirs []string) []string {
+	var paths []string
+	seen := make(map[string]struct{})
+	for _, dir := range dirs {
+		filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+			if err != nil || f.IsDir() {
+				return nil
+			}
+			if !isGoPlugin(path) && !(strings.HasPrefix(f.Name(), ExecPrefix) && f.Mode()&0111 != 0) {
+				return nil
+			}
+			key := path
+			if abs, err := filepath.Abs(path); err == nil {
+				if real, err := filepath.EvalSymlinks(abs); err == nil {
+					key = real
+				}
+			}
+			if _, ok := seen[key]; ok {
+				return nil
+			}
+			seen[key] = struct{}{}
+			paths = append(paths, path)
+			return nil
+		})
+	}
+	return paths
+}
+
+// Open checks the manifest of a plugin file and opens the plugin, exec
+// plugins are started.
+func Open(path string) (l Loaded, err error) {
+	l.Path = path
+	if l.Manifest, err = ReadManifest(path); err != nil {
+		return l, err
+	}
+	if !isGoPlugin(path) {
+		p, err := StartExec(exec.Command(path))
+		if err != nil {
+			return l, err
+		}
+		if info := p.Info(); info.Name != l.Manifest.Name || info.Version != l.Manifest.Version {
+			p.Close()
+			return l, fmt.Errorf("plugin is %s %s, its manifest %s %s", info.Name, info.Version, l.Manifest.Name, l.Manifest.Version)
+		}
+		l.Plugin = p
+		return l, nil
+	}
+	defer func() {
+		// plugins built against other dependencies may panic
+		if r := recover(); r != nil {
+			err = fmt.Errorf("plugin panicked: %v", r)
+		}
+	}()
+	p, err := plugin.Open(path)
+	if err != nil {
+		return l, err
+	}
+	New, err := p.Lookup("New")
+	if err != nil {
+		return l, err
+	}
+	newPlugin, ok := New.(func() Plugin)
+	if !ok {
+		return l, fmt.Errorf("New is %T, want func() plugins.Plugin", New)
+	}
+	l.Plugin = newPlugin()
+	l.Objects = l.Plugin.ListObjects()
+	return l, nil
+}
+
+// Rejected is a plugin file which could not be loaded.
+type Rejected struct {
+	Path string
+	Err  error
+}
+
+// Verify opens the plugin files, all plugins of Dirs if none are given, and
+// returns those which are loaded and those which are rejected. Exec plugins
+// are stopped again.
+func Verify(paths []string) ([]Loaded, []Rejected) {
+	if len(paths) == 0 {
+		paths = Find(Dirs)
+	}
+	var ok []Loaded
+	var rejected []Rejected
+	for _, path := range paths {
+		l, err := Open(path)
+		if err != nil {
+			rejected = append(rejected, Rejected{Path: path, Err: err})
+			continue
+		}
+		if p, isExec := l.Plugin.(*ExecPlugin); isExec {
+			p.Close()
+		}
+		ok = append(ok, l)
+	}
+	return ok, rejected
+}
@@ -0,0 +1,59 @@
+package plugins
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReadManifest(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		file     string
+		manifest string
+		wantErr  bool
+	}{
+		{name: "go plugin", file: "appsv1.so", manifest: "name: appsv1\nversion: 0.1.0\napiVersion: " + APIVersion + "\ngoVersion: " + runtime.Version()},
+		{name: "exec plugin", file: "kubewatch-plugin-a", manifest: "name: a\nversion: 1.0.0\napiVersion: " + APIVersion},
+		{name: "no manifest", file: "none.so", wantErr: true},
+		{name: "other api version", file: "b.so", manifest: "name: b\nversion: 1.0.0\napiVersion: kubewatch.plugins/v2", wantErr: true},
+		{name: "other go version", file: "c.so", manifest: "name: c\nversion: 1.0.0\napiVersion: " + APIVersion + "\ngoVersion: go1.1", wantErr: true},
+		{name: "no version", file: "d.so", manifest: "name: d\napiVersion: " + APIVersion, wantErr: true},
+		{name: "unknown field", file: "e.so", manifest: "name: e\nversion: 1.0.0\napiVersion: " + APIVersion + "\nfoo: bar", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			if tt.manifest != "" {
+				if err := os.WriteFile(ManifestPath(path), []byte(tt.manifest), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := ReadManifest(path); (err != nil) != tt.wantErr {
+				t.Errorf("ReadManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{
+		"appsv1.so":          0644,
+		"appsv1.yaml":        0644,
+		"kubewatch-plugin-a": 0755,
+		"kubewatch-plugin-b": 0644,
+		"other":              0755,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := Find([]string{dir, filepath.Join(dir, ".."), dir, filepath.Join(dir, "missing")})
+	want := []string{filepath.Join(dir, "appsv1.so"), filepath.Join(dir, "kubewatch-plugin-a")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+}
@@ -2,73 +2,122 @@ package plugins
 
 import (
 	"fmt"
-	"log"
 	"os"
-	"path/filepath"
-	"plugin"
-	"strings"
+	"sort"
+	"sync"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var scheme = runtime.NewScheme()
 var objectMap = make(map[string]Object)
+var loaded []Loaded
+var rejected []Rejected
+var loadOnce sync.Once
 
-func init() {
-	exePath, err := os.Executable()
-	if err != nil {
-		log.Fatal(err)
-	}
-	binaryPath, err := filepath.EvalSymlinks(filepath.Dir(exePath))
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, path := range []string{
-		"./plugins",
-		fmt.Sprintf("%s/../plugins", binaryPath),
-	} {
-		if err := filepath.Walk(path, func(src string, f os.FileInfo, e error) error {
-			if f == nil {
-				return nil
-			}
-			if f.IsDir() {
-				return nil
-			}
-			if !strings.HasSuffix(src, ".so") {
-				return nil
-			}
-			p, err := plugin.Open(src)
-			if err != nil {
-				return err
-			}
-			New, err := p.Lookup("New")
-			if err != nil {
-				return err
-			}
-			pl := New.(func() Plugin)()
-			return AddPlugin(pl)
-		}); err != nil {
-			fmt.Println(err)
+// Loaded is a plugin loaded from a file.
+type Loaded struct {
+	Path     string
+	Manifest Manifest
+	Plugin   Plugin
+	Objects  []Object
+}
+
+// load loads the plugins on first use, plugins can't be opened while this
+// package is initialized as they import it.
+func load() {
+	loadOnce.Do(loadPlugins)
+}
+
+func loadPlugins() {
+	for _, path := range Find(Dirs) {
+		l, err := Open(path)
+		if err == nil {
+			err = addLoaded(l)
+		}
+		if err != nil {
+			rejected = append(rejected, Rejected{Path: path, Err: err})
+			fmt.Fprintf(os.Stderr, "plugin %s rejected: %v\n", path, err)
 		}
 	}
 }
 
 func GetScheme() *runtime.Scheme {
+	load()
 	return scheme
 }
 
 func AddPlugin(p Plugin) error {
-	objects := p.ListObjects()
-	for _, o := range objects {
+	return addLoaded(Loaded{Plugin: p, Objects: p.ListObjects()})
+}
+
+func addLoaded(l Loaded) error {
+	for _, o := range l.Objects {
 		name := o.Name
 		objectMap[name] = o
 		if shortName := o.ShortName; shortName != "" {
 			objectMap[shortName] = o
 		}
 	}
-	return p.AddToScheme(scheme)
+	if err := l.Plugin.AddToScheme(scheme); err != nil {
+		return err
+	}
+	loaded = append(loaded, l)
+	resetHooks()
+	return nil
 }
 
 func GetObjectMap() map[string]Object {
+	load()
 	return objectMap
 }
+
+// List returns the loaded plugins in the order they were loaded.
+func List() []Loaded {
+	load()
+	return loaded
+}
+
+// ListRejected returns the plugin files which could not be loaded.
+func ListRejected() []Rejected {
+	load()
+	return rejected
+}
+
+// AddToScheme registers the types of all loaded plugins.
+func AddToScheme(s *runtime.Scheme) error {
+	load()
+	for _, l := range loaded {
+		if err := l.Plugin.AddToScheme(s); err != nil {
+			return fmt.Errorf("plugin %s: %v", l.Path, err)
+		}
+	}
+	return nil
+}
+
+// GVK returns the group, version and kind the object is registered with by
+// its plugin.
+func (o Object) GVK() (schema.GroupVersionKind, error) {
+	gvks, _, err := scheme.ObjectKinds(o.Object)
+	if err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	sort.Slice(gvks, func(i, j int) bool { return gvks[i].String() < gvks[j].String() })
+	return gvks[0], nil
+}
+
+// Kinds returns the objects of the loaded plugins by their group, version
+// and kind, objects which aren't registered are skipped.
+func Kinds() map[schema.GroupVersionKind]Object {
+	load()
+	kinds := make(map[schema.GroupVersionKind]Object)
+	for _, l := range loaded {
+		for _, o := range l.Objects {
+			if gvk, err := o.GVK(); err == nil {
+				kinds[gvk] = o
+			}
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,129 @@
+package plugins
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// ProtocolVersion is the version of the wire protocol of exec plugins. Exec
+// plugins are executables exchanging one JSON message per line over their
+// stdin and stdout: kubewatch sends requests and notifications, the plugin
+// answers every request with a response of the same id, in order.
+const ProtocolVersion = "kubewatch.plugins/v1"
+
+// The methods of the protocol. A plugin advertises the hooks it implements
+// in its handshake, kubewatch sends only those.
+const (
+	// MethodHandshake is the first request, with Handshake params and an
+	// Info result.
+	MethodHandshake = "handshake"
+	// MethodNormalize has ObjectParams and an ObjectResult.
+	MethodNormalize = "normalize"
+	// MethodSummarize has ObjectParams and a SummaryResult.
+	MethodSummarize = "summarize"
+	// MethodMetrics has ObjectParams and a MetricsResult.
+	MethodMetrics = "metrics"
+	// MethodFilter has FilterParams and a FilterResult.
+	MethodFilter = "filter"
+	// MethodRecords is a notification without response, with the
+	// RecordsParams of every reported event.
+	MethodRecords = "records"
+)
+
+// Request is a request, or a notification if it has no id.
+type Request struct {
+	ID     int64           `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the response of a request.
+type Response struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Handshake are the params of the handshake.
+type Handshake struct {
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+// Info describes an exec plugin, it is the result of the handshake.
+type Info struct {
+	Name            string `json:"name"`
+	Version         string `json:"version,omitempty"`
+	ProtocolVersion string `json:"protocolVersion"`
+	// Kinds the hooks are called for, all kinds if empty.
+	Kinds []Kind `json:"kinds,omitempty"`
+	// Hooks are the methods the plugin implements.
+	Hooks []string `json:"hooks,omitempty"`
+}
+
+// Kind selects kinds by group, and by version and kind unless they are
+// empty.
+type Kind struct {
+	Group   string `json:"group"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+}
+
+func (k Kind) matches(gvk schema.GroupVersionKind) bool {
+	return k.Group == gvk.Group && (k.Version == "" || k.Version == gvk.Version) && (k.Kind == "" || k.Kind == gvk.Kind)
+}
+
+func (k Kind) String() string {
+	s := k.Kind
+	if s == "" {
+		s = "*"
+	}
+	if k.Version != "" {
+		s += "." + k.Version
+	}
+	if k.Group != "" {
+		s += "." + k.Group
+	}
+	return s
+}
+
+// ObjectParams pass an object.
+type ObjectParams struct {
+	Object json.RawMessage `json:"object"`
+}
+
+// ObjectResult returns an object.
+type ObjectResult struct {
+	Object json.RawMessage `json:"object"`
+}
+
+// SummaryResult returns the summary of an object, ok false to use the
+// summary fields.
+type SummaryResult struct {
+	Summary string `json:"summary"`
+	OK      bool   `json:"ok"`
+}
+
+// MetricsResult returns the metric values of an object.
+type MetricsResult struct {
+	Samples []Sample `json:"samples"`
+}
+
+// FilterParams pass the objects of an event, Old is null for created
+// objects and New for deleted ones.
+type FilterParams struct {
+	Old json.RawMessage `json:"old"`
+	New json.RawMessage `json:"new"`
+}
+
+// FilterResult reports whether the event is reported.
+type FilterResult struct {
+	Pass bool `json:"pass"`
+}
+
+// RecordsParams pass the records of a reported event.
+type RecordsParams struct {
+	Records []output.Record `json:"records"`
+}
@@ -0,0 +1,148 @@
+package plugins
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+// RecordHandler receives the records of the reported events in an exec
+// plugin.
+type RecordHandler interface {
+	HandleRecords(records []output.Record)
+}
+
+// ServeStdio runs an exec plugin over stdin and stdout, see Serve.
+func ServeStdio(info Info, p interface{}) error {
+	return Serve(info, p, os.Stdin, os.Stdout)
+}
+
+// Serve runs an exec plugin, answering the requests read from in with the
+// hooks p implements until in is closed: Normalizer, Summarizer,
+// MetricExtractor, EventFilter and RecordHandler. The hooks get unstructured
+// objects, the hooks of info are set from the implemented ones.
+func Serve(info Info, p interface{}, in io.Reader, out io.Writer) error {
+	info.ProtocolVersion = ProtocolVersion
+	info.Hooks = hookNames(p)
+	if _, ok := p.(RecordHandler); ok {
+		info.Hooks = append(info.Hooks, MethodRecords)
+	}
+	enc := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, maxMessageSize)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			return fmt.Errorf("invalid request: %v", err)
+		}
+		result, err := serveRequest(info, p, req)
+		if req.ID == 0 {
+			// notifications have no response
+			continue
+		}
+		r := Response{ID: req.ID}
+		if err == nil {
+			r.Result, err = json.Marshal(result)
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func serveRequest(info Info, p interface{}, req Request) (interface{}, error) {
+	switch req.Method {
+	case MethodHandshake:
+		var h Handshake
+		if err := json.Unmarshal(req.Params, &h); err != nil {
+			return nil, err
+		}
+		if h.ProtocolVersion != ProtocolVersion {
+			return nil, fmt.Errorf("protocol version %q is not supported, want %s", h.ProtocolVersion, ProtocolVersion)
+		}
+		return info, nil
+	case MethodNormalize:
+		if h, ok := p.(Normalizer); ok {
+			obj, err := objectParams(req)
+			if err != nil {
+				return nil, err
+			}
+			b, err := json.Marshal(h.Normalize(obj))
+			return ObjectResult{Object: b}, err
+		}
+	case MethodSummarize:
+		if h, ok := p.(Summarizer); ok {
+			obj, err := objectParams(req)
+			if err != nil {
+				return nil, err
+			}
+			summary, ok := h.Summarize(obj)
+			return SummaryResult{Summary: summary, OK: ok}, nil
+		}
+	case MethodMetrics:
+		if h, ok := p.(MetricExtractor); ok {
+			obj, err := objectParams(req)
+			if err != nil {
+				return nil, err
+			}
+			return MetricsResult{Samples: h.Metrics(obj)}, nil
+		}
+	case MethodFilter:
+		if h, ok := p.(EventFilter); ok {
+			var params FilterParams
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return nil, err
+			}
+			objOld, err := decodeObject(params.Old)
+			if err != nil {
+				return nil, err
+			}
+			objNew, err := decodeObject(params.New)
+			if err != nil {
+				return nil, err
+			}
+			return FilterResult{Pass: h.Filter(objOld, objNew)}, nil
+		}
+	case MethodRecords:
+		if h, ok := p.(RecordHandler); ok {
+			var params RecordsParams
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return nil, err
+			}
+			h.HandleRecords(params.Records)
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("method %q is not implemented", req.Method)
+}
+
+func objectParams(req Request) (client.Object, error) {
+	var params ObjectParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, err
+	}
+	return decodeObject(params.Object)
+}
+
+// decodeObject decodes an object as unstructured object, nil if it is null.
+func decodeObject(b json.RawMessage) (client.Object, error) {
+	if len(b) == 0 || string(b) == "null" {
+		return nil, nil
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
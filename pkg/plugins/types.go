@@ -5,6 +5,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// Plugin registers typed objects, it may implement the optional hooks of
+// hooks.go.
 type Plugin interface {
 	AddToScheme(s *runtime.Scheme) error
 	ListObjects() []Object
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -27,6 +28,12 @@ import (
 var (
 	logger     = log.FromContext(context.Background())
 	Kubeconfig string
+	// CacheDir is the directory of the discovery and HTTP caches, shared
+	// with kubectl by default.
+	CacheDir string
+	// DiscoveryCacheTTL is how long cached discovery is used without asking
+	// the API server.
+	DiscoveryCacheTTL time.Duration
 )
 
 func ListObjectFields(ctx context.Context, obj interface{}, split string) []string {
@@ -0,0 +1,314 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/nfyxhan/kubewatch/pkg/metrics"
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+const (
+	defaultBatchSize     = 10
+	defaultBatchInterval = 5 * time.Second
+	defaultMaxRetries    = 5
+	defaultBackoff       = time.Second
+	defaultTimeout       = 10 * time.Second
+	defaultQueueSize     = 1000
+)
+
+// Config of a webhook sink, usually loaded from the webhooks key of the
+// config file.
+type Config struct {
+	Name    string            `json:"name,omitempty"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Paths are regular expressions, only changes of matching paths are
+	// sent. All changes are sent if empty.
+	Paths []string `json:"paths,omitempty"`
+	// Template renders the request body from a Payload. The payload is
+	// sent as JSON if empty.
+	Template      string        `json:"template,omitempty"`
+	BatchSize     int           `json:"batchSize,omitempty"`
+	BatchInterval time.Duration `json:"batchInterval,omitempty"`
+	MaxRetries    int           `json:"maxRetries,omitempty"`
+	Backoff       time.Duration `json:"backoff,omitempty"`
+	Timeout       time.Duration `json:"timeout,omitempty"`
+	// QueueSize is the number of records waiting to be sent, further
+	// records are dropped, or written to the dead letter file, so a slow
+	// endpoint doesn't stall the watch.
+	QueueSize int `json:"queueSize,omitempty"`
+	// DeadLetter is a file the payloads are appended to when they could
+	// not be delivered after MaxRetries.
+	DeadLetter string `json:"deadLetter,omitempty"`
+}
+
+// Payload is the data a batch of changes is rendered from.
+type Payload struct {
+	Name    string          `json:"name,omitempty"`
+	Records []output.Record `json:"records"`
+}
+
+// Sink is an output.Writer posting batches of matched changes to a webhook.
+type Sink struct {
+	config   Config
+	paths    []*regexp.Regexp
+	template *template.Template
+	client   *http.Client
+	records  chan output.Record
+	cancel   context.CancelFunc
+	done     chan struct{}
+	log      logr.Logger
+	// dropping is set while records are dropped, so only the first drop
+	// of a series is logged.
+	dropping int32
+}
+
+// New returns a Sink sending its batches in the background until ctx is done
+// or it is closed.
+func New(ctx context.Context, config Config) (*Sink, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("webhook %s: url is required", config.Name)
+	}
+	if config.Method == "" {
+		config.Method = http.MethodPost
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaultBatchSize
+	}
+	if config.BatchInterval <= 0 {
+		config.BatchInterval = defaultBatchInterval
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = defaultBackoff
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultQueueSize
+	}
+	if config.QueueSize < config.BatchSize {
+		config.QueueSize = config.BatchSize
+	}
+	s := &Sink{
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+		records: make(chan output.Record, config.QueueSize),
+		done:    make(chan struct{}),
+		log:     log.FromContext(ctx).WithValues("webhook", config.Name, "url", config.URL),
+	}
+	for _, p := range config.Paths {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid path %q: %v", config.Name, p, err)
+		}
+		s.paths = append(s.paths, r)
+	}
+	if config.Template != "" {
+		t, err := template.New(config.Name).Funcs(template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				bf := bytes.NewBuffer(nil)
+				enc := json.NewEncoder(bf)
+				enc.SetEscapeHTML(false)
+				err := enc.Encode(v)
+				return strings.TrimSuffix(bf.String(), "\n"), err
+			},
+		}).Parse(config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: invalid template: %v", config.Name, err)
+		}
+		s.template = t
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	go s.run(ctx)
+	return s, nil
+}
+
+// Close sends the pending batch and stops the sink.
+func (s *Sink) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// Write queues the matched records of the event without blocking, records
+// which don't fit into the queue are dropped.
+func (s *Sink) Write(e output.Event) error {
+	for _, r := range e.Records() {
+		if !s.match(r.Path) {
+			continue
+		}
+		select {
+		case <-s.done:
+			return fmt.Errorf("webhook %s: closed", s.config.Name)
+		default:
+		}
+		select {
+		case s.records <- r:
+			atomic.StoreInt32(&s.dropping, 0)
+		default:
+			s.drop(r)
+		}
+	}
+	return nil
+}
+
+// drop counts a record the queue is full for and writes it to the dead
+// letter file.
+func (s *Sink) drop(r output.Record) {
+	metrics.GetMetricsWebhookDropped().WithLabelValues(s.config.Name).Inc()
+	if atomic.CompareAndSwapInt32(&s.dropping, 0, 1) {
+		s.log.Info("queue is full, dropping records", "queueSize", s.config.QueueSize)
+	}
+	body, err := s.render([]output.Record{r})
+	if err != nil {
+		return
+	}
+	if err := s.deadLetter(body); err != nil {
+		s.log.Error(err, "failed to write dead letter", "file", s.config.DeadLetter)
+	}
+}
+
+func (s *Sink) match(path string) bool {
+	if len(s.paths) == 0 {
+		return true
+	}
+	for _, p := range s.paths {
+		if p.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sink) run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.config.BatchInterval)
+	defer ticker.Stop()
+	batch := make([]output.Record, 0, s.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		s.send(ctx, batch)
+		batch = make([]output.Record, 0, s.config.BatchSize)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case r := <-s.records:
+					batch = append(batch, r)
+					continue
+				default:
+				}
+				break
+			}
+			flush()
+			return
+		case r := <-s.records:
+			batch = append(batch, r)
+			if len(batch) >= s.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func (s *Sink) render(records []output.Record) ([]byte, error) {
+	payload := Payload{
+		Name:    s.config.Name,
+		Records: records,
+	}
+	if s.template == nil {
+		return json.Marshal(payload)
+	}
+	bf := bytes.NewBuffer(nil)
+	if err := s.template.Execute(bf, payload); err != nil {
+		return nil, err
+	}
+	return bf.Bytes(), nil
+}
+
+func (s *Sink) send(ctx context.Context, records []output.Record) {
+	body, err := s.render(records)
+	if err != nil {
+		s.log.Error(err, "failed to render payload")
+		return
+	}
+	backoff := s.config.Backoff
+	for i := 0; i <= s.config.MaxRetries; i++ {
+		if i > 0 {
+			// the last batch is still tried on shutdown, without waiting
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = s.post(body); err == nil {
+			return
+		}
+		s.log.Info("failed to send payload", "attempt", i+1, "err", err.Error())
+	}
+	s.log.Error(err, "giving up sending payload", "records", len(records))
+	if err := s.deadLetter(body); err != nil {
+		s.log.Error(err, "failed to write dead letter", "file", s.config.DeadLetter)
+	}
+}
+
+func (s *Sink) post(body []byte) error {
+	req, err := http.NewRequest(s.config.Method, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Sink) deadLetter(body []byte) error {
+	if s.config.DeadLetter == "" {
+		return nil
+	}
+	f, err := os.OpenFile(s.config.DeadLetter, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b := bytes.TrimRight(body, "\n")
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
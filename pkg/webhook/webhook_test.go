@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nfyxhan/kubewatch/pkg/output"
+)
+
+func TestSink(t *testing.T) {
+	e := output.Event{
+		Kind: "Deployment",
+		Name: "podinfo",
+		Changes: []output.Change{
+			{Path: "status/readyReplicas", From: 1, To: 2, Op: "update"},
+			{Path: "spec/replicas", From: 1, To: 2, Op: "update"},
+		},
+	}
+	tests := []struct {
+		name           string
+		config         Config
+		status         int
+		want           []string
+		wantDeadLetter string
+	}{
+		{
+			name: "template",
+			config: Config{
+				Paths:    []string{"^status/"},
+				Template: `{"text":{{range .Records}}{{json (printf "%s/%s %s: %v -> %v" .Kind .Name .Path .From .To)}}{{end}}}`,
+			},
+			status: http.StatusOK,
+			want:   []string{`{"text":"Deployment/podinfo status/readyReplicas: 1 -> 2"}`},
+		},
+		{
+			name:           "dead letter",
+			config:         Config{Name: "slack", Paths: []string{"^spec/"}, MaxRetries: 1, Backoff: time.Millisecond},
+			status:         http.StatusInternalServerError,
+			want:           []string{`{"name":"slack","records":[{"time":"0001-01-01T00:00:00Z","group":"","version":"","kind":"Deployment","name":"podinfo","path":"spec/replicas","from":1,"to":2,"op":"update"}]}`},
+			wantDeadLetter: `{"name":"slack","records":[{"time":"0001-01-01T00:00:00Z","group":"","version":"","kind":"Deployment","name":"podinfo","path":"spec/replicas","from":1,"to":2,"op":"update"}]}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var got []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+				lock.Lock()
+				got = append(got, string(b))
+				lock.Unlock()
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+			tt.config.URL = srv.URL
+			tt.config.DeadLetter = filepath.Join(t.TempDir(), "dead-letter.ndjson")
+			s, err := New(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if err := s.Write(e); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			s.Close()
+			lock.Lock()
+			defer lock.Unlock()
+			want := tt.want
+			if tt.status != http.StatusOK {
+				// every retry sends the same payload
+				want = []string{want[0], want[0]}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("payload got = %v, want %v", got, want)
+			}
+			b, _ := os.ReadFile(tt.config.DeadLetter)
+			if dl := string(b); dl != tt.wantDeadLetter {
+				t.Errorf("dead letter got = %v, want %v", dl, tt.wantDeadLetter)
+			}
+		})
+	}
+}
+
+// TestSinkFullQueue checks writes don't block while the endpoint hangs, the
+// records which don't fit into the queue are written to the dead letter file.
+func TestSinkFullQueue(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	deadLetter := filepath.Join(t.TempDir(), "dead-letter.ndjson")
+	s, err := New(context.Background(), Config{
+		URL:        srv.URL,
+		BatchSize:  1,
+		QueueSize:  2,
+		MaxRetries: 1,
+		Backoff:    time.Millisecond,
+		DeadLetter: deadLetter,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	e := output.Event{
+		Kind:    "Deployment",
+		Name:    "podinfo",
+		Changes: []output.Change{{Path: "spec/replicas", From: 1, To: 2, Op: "update"}},
+	}
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := 0; i < 10; i++ {
+			if err := s.Write(e); err != nil {
+				t.Errorf("Write() error = %v", err)
+			}
+		}
+	}()
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write() blocked on a hanging endpoint")
+	}
+	b, _ := os.ReadFile(deadLetter)
+	if n := bytes.Count(b, []byte("\n")); n < 10-1-2 {
+		t.Errorf("dead letter got %d records, want at least %d", n, 10-1-2)
+	}
+	close(release)
+	s.Close()
+}
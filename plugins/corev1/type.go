@@ -2,7 +2,9 @@ package main
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/nfyxhan/kubewatch/pkg/plugins"
 )
@@ -30,6 +32,11 @@ func (p *Plugin) ListObjects() []plugins.Object {
 			ShortName:  "ns",
 			Object:     &v1.Namespace{},
 			ObjectList: &v1.NamespaceList{},
+		}, {
+			Name:       "node",
+			ShortName:  "no",
+			Object:     &v1.Node{},
+			ObjectList: &v1.NodeList{},
 		}, {
 			Name:       "pod",
 			Object:     &v1.Pod{},
@@ -37,3 +44,17 @@ func (p *Plugin) ListObjects() []plugins.Object {
 		},
 	}
 }
+
+// Normalize drops the heartbeat times of node conditions, which change every
+// few seconds.
+func (p *Plugin) Normalize(obj client.Object) client.Object {
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		return obj
+	}
+	node = node.DeepCopy()
+	for i := range node.Status.Conditions {
+		node.Status.Conditions[i].LastHeartbeatTime = metav1.Time{}
+	}
+	return node
+}
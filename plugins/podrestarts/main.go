@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/nfyxhan/kubewatch/pkg/plugins"
+)
+
+// Plugin exports the container restarts of pods and reports only updates
+// which restarted a container.
+type Plugin struct {
+}
+
+func restarts(obj client.Object) int64 {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return 0
+	}
+	statuses, _, _ := unstructured.NestedSlice(u.Object, "status", "containerStatuses")
+	var n int64
+	for _, s := range statuses {
+		if m, ok := s.(map[string]interface{}); ok {
+			c, _, _ := unstructured.NestedInt64(m, "restartCount")
+			n += c
+		}
+	}
+	return n
+}
+
+func (p *Plugin) Metrics(obj client.Object) []plugins.Sample {
+	return []plugins.Sample{{Name: "container_restarts", Value: float64(restarts(obj))}}
+}
+
+func (p *Plugin) Filter(objOld, objNew client.Object) bool {
+	if objOld == nil || objNew == nil {
+		return true
+	}
+	return restarts(objNew) > restarts(objOld)
+}
+
+func main() {
+	info := plugins.Info{
+		Name:    "pod-restarts",
+		Version: "0.1.0",
+		Kinds:   []plugins.Kind{{Group: "", Kind: "Pod"}},
+	}
+	if err := plugins.ServeStdio(info, &Plugin{}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}